@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"tibia-nemesis-api/internal/config"
+	"tibia-nemesis-api/internal/models"
+	"tibia-nemesis-api/internal/service"
+)
+
+// backtest replays the stored history through the inclusion ranges, either the
+// active ones from the database or a candidate metadata file
+func backtest(cfg config.Config, args []string) {
+	fs := flag.NewFlagSet("backtest", flag.ExitOnError)
+	horizon := fs.Int("horizon", 3, "days after an observation in which a kill counts")
+	world := fs.String("world", "", "only replay this world")
+	boss := fs.String("boss", "", "only replay this boss (name, ID or alias)")
+	metadataPath := fs.String("metadata", "", "metadata file to evaluate instead of the metadata in the database")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	fs.Parse(args)
+
+	st, err := openStore(cfg)
+	if err != nil {
+		fatalf("store init: %v", err)
+	}
+	defer st.Close()
+
+	var metadata *models.BossMetadataFile
+	if *metadataPath != "" {
+		metadata, err = service.LoadBossMetadata(*metadataPath)
+	} else {
+		metadata, err = service.StoredMetadata(st)
+	}
+	if err != nil {
+		fatalf("boss metadata: %v", err)
+	}
+	if *world != "" {
+		worlds := service.NewWorldRegistry(cfg.Worlds)
+		if stored, err := st.GetWorlds(); err == nil {
+			worlds.Add(stored...)
+		}
+		if canonical, ok := worlds.Canonical(*world); ok {
+			*world = canonical
+		}
+	}
+
+	report, err := service.Backtest(st, metadata, service.BacktestOptions{Horizon: *horizon, World: *world, Boss: *boss})
+	if err != nil {
+		fatalf("backtest: %v", err)
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+		return
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "boss\tdays\tkilled within %dd\tspawnable when killed\trecall\tspawnable days\tno kill\tnoise\t\n", report.Horizon)
+	for _, r := range append(report.Bosses, report.Total) {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%.2f\t%d\t%d\t%.2f\t\n",
+			r.Name, r.Days, r.Positive, r.Hits, r.Recall, r.SpawnableDays, r.NoiseDays, r.NoiseRate)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,377 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"tibia-nemesis-api/internal/config"
+	"tibia-nemesis-api/internal/models"
+	"tibia-nemesis-api/internal/scraper"
+	"tibia-nemesis-api/internal/service"
+	"tibia-nemesis-api/internal/store"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The commands below work on the database directly, so they can be run from
+// cron or a shell without a server. SQLite's WAL mode lets them share the
+// database with a running server.
+
+// openService opens the configured database and the service on top of it.
+// The returned function closes the database.
+func openService(cfg config.Config) (*service.Service, func()) {
+	st, err := openStore(cfg)
+	if err != nil {
+		fatalf("store init: %v", err)
+	}
+	svc, err := service.New(st, scraper.New(cfg), cfg)
+	if err != nil {
+		st.Close()
+		fatalf("service init: %v", err)
+	}
+	return svc, func() { st.Close() }
+}
+
+// newFlagSet returns a flag set whose usage line is the given synopsis
+func newFlagSet(name, synopsis string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: tibia-nemesis-api %s %s\n\n", name, synopsis)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// printJSON writes v to stdout as indented JSON
+func printJSON(v any) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+// refresh scrapes the given worlds, or every known world like the scheduled
+// run. It exits with status 1 when a world fails.
+func refresh(cfg config.Config, args []string) {
+	fs := newFlagSet("refresh", "[world...]")
+	fs.Parse(args)
+
+	svc, closeStore := openService(cfg)
+	defer closeStore()
+	ctx := context.Background()
+
+	var err error
+	if fs.NArg() == 0 {
+		go svc.StartDigests()
+		err = svc.RefreshAll(ctx)
+		svc.FlushDigests()
+	} else {
+		var failed []error
+		for _, w := range fs.Args() {
+			if err := svc.RefreshWorld(ctx, w); err != nil {
+				failed = append(failed, fmt.Errorf("%s: %w", w, err))
+				continue
+			}
+			fmt.Printf("Refreshed %s\n", w)
+		}
+		err = errors.Join(failed...)
+	}
+	if err != nil {
+		closeStore()
+		fatalf("refresh: %v", err)
+	}
+}
+
+// worlds lists the worlds with data
+func worlds(cfg config.Config, args []string) {
+	fs := newFlagSet("worlds", "[-json]")
+	asJSON := fs.Bool("json", false, "print the worlds as JSON")
+	fs.Parse(args)
+
+	svc, closeStore := openService(cfg)
+	defer closeStore()
+	list, err := svc.Worlds(context.Background())
+	if err != nil {
+		closeStore()
+		fatalf("worlds: %v", err)
+	}
+	if *asJSON {
+		printJSON(list)
+		return
+	}
+	for _, w := range list {
+		fmt.Println(w)
+	}
+}
+
+// bosses prints the bosses of a world as the API returns them
+func bosses(cfg config.Config, args []string) {
+	fs := newFlagSet("bosses", "[-json] <world>")
+	asJSON := fs.Bool("json", false, "print the response as JSON")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	svc, closeStore := openService(cfg)
+	defer closeStore()
+	resp, err := svc.Bosses(context.Background(), fs.Arg(0))
+	if err != nil {
+		closeStore()
+		fatalf("bosses: %v", err)
+	}
+	if *asJSON {
+		printJSON(resp)
+		return
+	}
+
+	fmt.Printf("%s, updated %s\n\n", resp.World, resp.UpdatedAt.UTC().Format(time.RFC3339))
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "boss\tpercent\tdays since kill\tspawnable\testimate\tconfidence\t\n")
+	for _, b := range resp.Bosses {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%t\t%s\t%s\t\n",
+			b.Name, cliInt(b.Percent), cliInt(b.DaysSinceKill), b.Spawnable, cliInt(b.EstimatedPercent), b.Confidence)
+	}
+	tw.Flush()
+}
+
+// history prints the stored observations of a boss, newest first
+func history(cfg config.Config, args []string) {
+	fs := newFlagSet("history", "[-limit n] [-json] <world> <boss>")
+	limit := fs.Int("limit", 30, "number of observations to print (0 for all)")
+	asJSON := fs.Bool("json", false, "print the observations as JSON")
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	svc, closeStore := openService(cfg)
+	defer closeStore()
+	list, err := svc.BossHistory(context.Background(), fs.Arg(0), fs.Arg(1), *limit)
+	if err != nil {
+		closeStore()
+		fatalf("history: %v", err)
+	}
+	if *asJSON {
+		printJSON(list)
+		return
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "observed\tpercent\tdays since kill\tno chance\t\n")
+	for _, o := range list {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%t\t\n",
+			o.UpdatedAt.UTC().Format(time.RFC3339), cliInt(o.Percent), cliInt(o.DaysSinceKill), o.IsNoChance)
+	}
+	tw.Flush()
+}
+
+// metadata runs the metadata subcommands; validate checks a metadata file
+// with the same rules as an import, without touching the database
+func metadata(cfg config.Config, args []string) {
+	if len(args) == 0 || args[0] != "validate" {
+		fmt.Fprintf(os.Stderr, "usage: tibia-nemesis-api metadata validate [file]\n")
+		os.Exit(2)
+	}
+	fs := newFlagSet("metadata validate", "[file]")
+	fs.Parse(args[1:])
+	if fs.NArg() > 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	path := cfg.MetadataPath
+	if fs.NArg() == 1 {
+		path = fs.Arg(0)
+	}
+
+	file, err := service.LoadBossMetadata(path)
+	var invalid service.MetadataErrors
+	if errors.As(err, &invalid) {
+		fmt.Printf("%s is invalid:\n", path)
+		for _, problem := range invalid {
+			fmt.Printf("  %s\n", problem)
+		}
+		os.Exit(1)
+	}
+	if err != nil {
+		fatalf("metadata: %v", err)
+	}
+	ranges := 0
+	for _, b := range file.Bosses {
+		if b.InclusionRange != nil {
+			ranges++
+		}
+	}
+	fmt.Printf("%s is valid: %d bosses, %d with an inclusion range, %d world groups\n",
+		path, len(file.Bosses), ranges, len(file.WorldGroups))
+}
+
+// migrate brings the database schema up to date and reports the versions
+func migrate(cfg config.Config, args []string) {
+	fs := newFlagSet("migrate", "")
+	fs.Parse(args)
+
+	from := 0
+	if _, err := os.Stat(cfg.DBPath); err == nil {
+		if from, err = store.SnapshotVersion(cfg.DBPath); err != nil {
+			fatalf("migrate: %v", err)
+		}
+	}
+	st, err := openStore(cfg)
+	if err != nil {
+		fatalf("migrate: %v", err)
+	}
+	st.Close()
+	to := store.SchemaVersion()
+	if from == to {
+		fmt.Printf("%s is up to date (schema version %d)\n", cfg.DBPath, to)
+		return
+	}
+	fmt.Printf("Migrated %s from schema version %d to %d\n", cfg.DBPath, from, to)
+}
+
+// printConfig prints the effective configuration as YAML, in the format of
+// the configuration file, with secrets redacted
+func printConfig(cfg config.Config, args []string) {
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Fprintf(os.Stderr, "usage: tibia-nemesis-api [flags] config print\n")
+		os.Exit(2)
+	}
+	newFlagSet("config print", "").Parse(args[1:])
+	if cfg.File != "" {
+		fmt.Printf("# read from %s\n", cfg.File)
+	}
+	enc := yaml.NewEncoder(os.Stdout)
+	enc.SetIndent(2)
+	if err := enc.Encode(cfg.Redacted()); err != nil {
+		fatalf("config: %v", err)
+	}
+	enc.Close()
+}
+
+// export writes stored observations as CSV, NDJSON or a JSON array, like
+// GET /api/v1/export
+func export(cfg config.Config, args []string) {
+	fs := newFlagSet("export", "[-world name] [-from date] [-to date] [-format csv|ndjson|json] [-o file]")
+	world := fs.String("world", "", "only export this world")
+	fromFlag := fs.String("from", "", "first day to export, YYYY-MM-DD")
+	toFlag := fs.String("to", "", "last day to export, YYYY-MM-DD")
+	format := fs.String("format", "csv", "output format: csv, ndjson or json")
+	out := fs.String("o", "", "file to write instead of stdout")
+	fs.Parse(args)
+
+	var from, to time.Time
+	var err error
+	if *fromFlag != "" {
+		if from, err = time.Parse("2006-01-02", *fromFlag); err != nil {
+			fatalf("export: -from must be a date like 2006-01-02")
+		}
+	}
+	if *toFlag != "" {
+		if to, err = time.Parse("2006-01-02", *toFlag); err != nil {
+			fatalf("export: -to must be a date like 2006-01-02")
+		}
+		// to is inclusive: the whole day is exported
+		to = to.AddDate(0, 0, 1)
+	}
+	switch *format {
+	case "csv", "ndjson", "json":
+	default:
+		fatalf("export: -format must be csv, ndjson or json, not %q", *format)
+	}
+
+	svc, closeStore := openService(cfg)
+	defer closeStore()
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			closeStore()
+			fatalf("export: %v", err)
+		}
+		defer f.Close()
+		w = f
+	}
+	bw := bufio.NewWriter(w)
+	rows := 0
+	err = exportRows(bw, *format, func(write func(models.SpawnChance) error) error {
+		return svc.Export(context.Background(), *world, from, to, func(o models.SpawnChance) error {
+			rows++
+			return write(o)
+		})
+	})
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err != nil {
+		closeStore()
+		fatalf("export: %v", err)
+	}
+	if *out != "" {
+		fmt.Fprintf(os.Stderr, "Exported %d observations to %s\n", rows, *out)
+	}
+}
+
+// exportRows writes the observations passed to write by each in the format,
+// with the same columns as the HTTP export
+func exportRows(w io.Writer, format string, each func(write func(models.SpawnChance) error) error) error {
+	switch format {
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"world", "name", "percent", "days_since_kill", "is_no_chance", "updated_at"})
+		err := each(func(o models.SpawnChance) error {
+			return cw.Write([]string{o.World, o.Name, cliInt(o.Percent), cliInt(o.DaysSinceKill),
+				strconv.FormatBool(o.IsNoChance), o.UpdatedAt.UTC().Format(time.RFC3339)})
+		})
+		cw.Flush()
+		if err != nil {
+			return err
+		}
+		return cw.Error()
+	case "ndjson":
+		enc := json.NewEncoder(w)
+		return each(func(o models.SpawnChance) error { return enc.Encode(o) })
+	}
+	sep := "["
+	err := each(func(o models.SpawnChance) error {
+		data, err := json.Marshal(o)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, sep); err != nil {
+			return err
+		}
+		sep = ","
+		_, err = w.Write(data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if sep == "[" {
+		_, err = io.WriteString(w, "[]\n")
+		return err
+	}
+	_, err = io.WriteString(w, "]\n")
+	return err
+}
+
+// cliInt renders a nullable number, empty when unknown
+func cliInt(v *int) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.Itoa(*v)
+}
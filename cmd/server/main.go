@@ -1,30 +1,122 @@
 package main
 
 import (
-	"log"
+	"flag"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
 	"time"
 
 	"tibia-nemesis-api/internal/config"
 	httpapi "tibia-nemesis-api/internal/http"
+	"tibia-nemesis-api/internal/logging"
 	"tibia-nemesis-api/internal/scraper"
 	"tibia-nemesis-api/internal/service"
 	"tibia-nemesis-api/internal/store"
 )
 
+const usage = `usage: tibia-nemesis-api [flags] [command] [command flags]
+
+commands:
+  serve                   run the API server (default)
+  refresh [world...]      scrape worlds now, all known worlds without arguments
+  worlds                  list the worlds with data
+  bosses <world>          print the bosses of a world
+  history <world> <boss>  print the stored observations of a boss
+  metadata validate       check a boss metadata file without importing it
+  migrate                 bring the database schema up to date
+  export                  write stored observations as CSV, NDJSON or JSON
+  backtest                score inclusion ranges against the stored history
+  restore <snapshot>      replace the database with a snapshot (stop the server first)
+  config print            print the effective configuration with secrets redacted
+
+Run a command with -h for its flags. The flags below override the configuration
+file and the environment.
+
+flags:
+`
+
+var commands = map[string]func(cfg config.Config, args []string){
+	"serve":    serve,
+	"refresh":  refresh,
+	"worlds":   worlds,
+	"bosses":   bosses,
+	"history":  history,
+	"metadata": metadata,
+	"migrate":  migrate,
+	"export":   export,
+	"backtest": backtest,
+	"restore":  restore,
+	"config":   printConfig,
+}
+
 func main() {
-	cfg := config.Load()
+	fs := flag.NewFlagSet("tibia-nemesis-api", flag.ExitOnError)
+	configFile := fs.String("config", "", "configuration file (default $CONFIG_FILE, or "+config.DefaultFile+" when it exists)")
+	overrides := config.RegisterFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(os.Args[1:])
+
+	cmd, args := "serve", fs.Args()
+	if len(args) > 0 {
+		cmd, args = args[0], args[1:]
+	}
+	if cmd == "help" {
+		fs.SetOutput(os.Stdout)
+		fs.Usage()
+		return
+	}
+	run, ok := commands[cmd]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", cmd)
+		fs.Usage()
+		os.Exit(2)
+	}
+	cfg, err := config.Load(*configFile, overrides)
+	if err != nil {
+		fatalf("config: %v", err)
+	}
+	logger, err := logging.New(os.Stderr, cfg.LogLevel, cfg.LogFormat)
+	if err != nil {
+		fatalf("config: %v", err)
+	}
+	slog.SetDefault(logger)
+	run(cfg, args)
+}
+
+// fatalf logs an error and exits with status 1
+func fatalf(format string, args ...any) {
+	slog.Error(fmt.Sprintf(format, args...))
+	os.Exit(1)
+}
 
-	st, err := store.NewSQLite(cfg.DBPath)
+func serve(cfg config.Config, args []string) {
+	newFlagSet("serve", "").Parse(args)
+	if cfg.File != "" {
+		slog.Info("configuration read", "path", cfg.File)
+	}
+
+	st, err := openStore(cfg)
 	if err != nil {
-		log.Fatalf("store init: %v", err)
+		fatalf("store init: %v", err)
 	}
 	defer st.Close()
 
 	scr := scraper.New(cfg)
-	svc := service.New(st, scr, cfg)
+	svc, err := service.New(st, scr, cfg)
+	if err != nil {
+		fatalf("service init: %v", err)
+	}
 	go svc.StartScheduler()
+	go svc.StartWebhooks()
+	go svc.StartDigests()
+	go svc.StartMetadataWatcher()
+	go svc.StartBackups()
+	go svc.StartMaintenance()
 
 	r := httpapi.NewRouter(svc)
 
@@ -34,9 +126,17 @@ func main() {
 		ReadHeaderTimeout: 10 * time.Second,
 	}
 
-	log.Printf("tibia-nemesis-api listening on :%s", cfg.Port)
+	slog.Info("tibia-nemesis-api listening", "addr", srv.Addr)
 	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.Printf("server error: %v", err)
-		os.Exit(1)
+		fatalf("server error: %v", err)
 	}
 }
+
+// openStore opens the configured database
+func openStore(cfg config.Config) (*store.SQLite, error) {
+	return store.NewSQLite(cfg.DBPath, store.Options{
+		BusyTimeout: cfg.DBBusyTimeout,
+		Synchronous: cfg.DBSynchronous,
+		ReadConns:   cfg.DBReadConns,
+	})
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"tibia-nemesis-api/internal/config"
+	"tibia-nemesis-api/internal/store"
+)
+
+// restore replaces the database with a snapshot written by a backup. The
+// snapshot may be given as a path or as a name in the backup directory.
+func restore(cfg config.Config, args []string) {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	dbPath := fs.String("db", cfg.DBPath, "database to replace")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: tibia-nemesis-api restore [-db path] <snapshot>\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	snapshot := fs.Arg(0)
+	if _, err := os.Stat(snapshot); os.IsNotExist(err) && filepath.Base(snapshot) == snapshot {
+		snapshot = filepath.Join(cfg.BackupDir, snapshot)
+	}
+	_, statErr := os.Stat(*dbPath)
+	version, err := store.Restore(snapshot, *dbPath)
+	if err != nil {
+		fatalf("restore: %v", err)
+	}
+	fmt.Printf("Restored %s (schema version %d) into %s\n", snapshot, version, *dbPath)
+	if statErr == nil {
+		fmt.Printf("The previous database was kept as %s.pre-restore\n", *dbPath)
+	}
+	if version < store.SchemaVersion() {
+		fmt.Printf("Migrations %d to %d run when the database is next opened\n", version+1, store.SchemaVersion())
+	}
+}
@@ -1,29 +1,466 @@
 package config
 
 import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
 	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
+// DefaultFile is read when no configuration file is given and it exists
+const DefaultFile = "config.yaml"
+
+// Config holds every setting. The YAML key of a field is also its setting key:
+// the environment variable is the key in upper case and the command line flag
+// the key with dashes, e.g. db_path, DB_PATH and -db-path.
 type Config struct {
-	Port      string
-	DBPath    string
-	RefreshAt string // HH:MM (24h)
-	TZ        string // IANA TZ, e.g. Europe/Berlin
+	File string `yaml:"-"` // Configuration file that was read, if any
+
+	Port      string   `yaml:"port"`
+	DBPath    string   `yaml:"db_path"`
+	RefreshAt string   `yaml:"refresh_at"` // HH:MM (24h)
+	TZ        string   `yaml:"tz"`         // IANA TZ, e.g. Europe/Berlin
+	Worlds    []string `yaml:"worlds"`     // Known worlds; empty uses the built-in list
+
+	DBBusyTimeout time.Duration `yaml:"db_busy_timeout"` // How long SQLite waits for a lock held by another connection
+	DBSynchronous string        `yaml:"db_synchronous"`  // PRAGMA synchronous: OFF, NORMAL, FULL or EXTRA
+	DBReadConns   int           `yaml:"db_read_conns"`   // Size of the read connection pool
+
+	SourceURL     string        `yaml:"source_url"` // Page scraped per world; %s is the lower case world name
+	SourceTimeout time.Duration `yaml:"source_timeout"`
+
+	MetadataPath          string        `yaml:"metadata_path"`
+	MetadataWatchInterval time.Duration `yaml:"metadata_watch_interval"` // How often to check the metadata file for changes; 0 disables
+
+	DiscordWebhooks   []DiscordWebhook `yaml:"discord_webhooks"`
+	DiscordHighChance int              `yaml:"discord_high_chance"` // Percent at which a boss is listed as high chance
+
+	WebhookMaxAttempts int           `yaml:"webhook_max_attempts"` // Delivery attempts before a webhook delivery fails
+	WebhookBackoff     time.Duration `yaml:"webhook_backoff"`      // Delay after the first failed attempt, doubled after each further one
+	WebhookMaxBackoff  time.Duration `yaml:"webhook_max_backoff"`  // Longest delay between attempts
+	WebhookTimeout     time.Duration `yaml:"webhook_timeout"`      // Timeout of each delivery attempt
+
+	AdminTokens []AdminToken `yaml:"admin_tokens"` // Bearer tokens for the admin, webhook, watch and alert endpoints; none disables them
+
+	BackupDir      string        `yaml:"backup_dir"`
+	BackupInterval time.Duration `yaml:"backup_interval"` // How often to snapshot the database; 0 disables scheduled backups
+	BackupKeep     int           `yaml:"backup_keep"`     // Snapshots to keep; older ones are removed, 0 keeps all
+
+	RetentionDays       int           `yaml:"retention_days"`       // Days of full-resolution history; older observations are downsampled, 0 keeps everything
+	RetentionDownsample string        `yaml:"retention_downsample"` // "daily" or "changes", see store.DownsampleObservations
+	MaintenanceInterval time.Duration `yaml:"maintenance_interval"` // How often retention, VACUUM and ANALYZE run; 0 disables scheduled runs
+
+	LogLevel  string `yaml:"log_level"`  // debug, info, warn or error
+	LogFormat string `yaml:"log_format"` // text or json
+}
+
+// AdminToken authenticates an admin. Name is recorded as the actor in audit entries.
+type AdminToken struct {
+	Name  string `yaml:"name"`
+	Token string `yaml:"token"`
+}
+
+// DiscordWebhook receives the digest for World, or for every world when World is empty
+type DiscordWebhook struct {
+	World string `yaml:"world,omitempty"`
+	URL   string `yaml:"url"`
+}
+
+// Errors lists every problem found in the configuration
+type Errors []string
+
+func (e Errors) Error() string {
+	return "invalid configuration:\n  " + strings.Join(e, "\n  ")
+}
+
+// Defaults returns the configuration used for settings that are not given
+func Defaults() Config {
+	return Config{
+		Port:                  "8080",
+		DBPath:                "tibia-nemesis-api.db",
+		RefreshAt:             "9:30",
+		TZ:                    "CET",
+		DBBusyTimeout:         5 * time.Second,
+		DBSynchronous:         "NORMAL",
+		DBReadConns:           4,
+		SourceURL:             "https://www.tibia-statistic.com/bosshunter/details/%s",
+		SourceTimeout:         15 * time.Second,
+		MetadataPath:          "bosses_metadata.yaml",
+		MetadataWatchInterval: 30 * time.Second,
+		DiscordHighChance:     50,
+		WebhookMaxAttempts:    8,
+		WebhookBackoff:        30 * time.Second,
+		WebhookMaxBackoff:     time.Hour,
+		WebhookTimeout:        10 * time.Second,
+		BackupDir:             "backups",
+		BackupInterval:        24 * time.Hour,
+		BackupKeep:            7,
+		RetentionDays:         90,
+		RetentionDownsample:   "daily",
+		MaintenanceInterval:   24 * time.Hour,
+		LogLevel:              "info",
+		LogFormat:             "text",
+	}
+}
+
+// Overrides holds settings given on the command line, by key
+type Overrides map[string]string
+
+// RegisterFlags adds a flag for every setting to fs. The flags given are in
+// the returned Overrides once fs is parsed.
+func RegisterFlags(fs *flag.FlagSet) Overrides {
+	o := Overrides{}
+	for _, s := range (&Config{}).settings() {
+		key := s.key
+		fs.Func(s.flag(), fmt.Sprintf("sets %s (env %s)", key, s.env()), func(v string) error {
+			o[key] = v
+			return nil
+		})
+	}
+	return o
+}
+
+// Load builds the configuration from the defaults, the configuration file,
+// the environment and the command line, each overriding the ones before, and
+// validates it. path is the file to read; when empty CONFIG_FILE is used, and
+// then DefaultFile if it exists.
+func Load(path string, flags Overrides) (Config, error) {
+	cfg := Defaults()
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	explicit := path != ""
+	if !explicit {
+		path = DefaultFile
+	}
+	if err := cfg.readFile(path); err == nil {
+		cfg.File = path
+	} else if explicit || !errors.Is(err, fs.ErrNotExist) {
+		return cfg, err
+	}
+
+	var problems Errors
+	for _, s := range cfg.settings() {
+		// An empty variable counts as unset
+		if v := os.Getenv(s.env()); v != "" {
+			if err := s.set(v); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", s.env(), err))
+			}
+		}
+		if v, ok := flags[s.key]; ok {
+			if err := s.set(v); err != nil {
+				problems = append(problems, fmt.Sprintf("-%s: %v", s.flag(), err))
+			}
+		}
+	}
+	// A setting that failed to parse keeps its previous value, so the rest is
+	// still worth checking
+	if err := cfg.Validate(); err != nil {
+		problems = append(problems, err.(Errors)...)
+	}
+	if len(problems) > 0 {
+		return cfg, problems
+	}
+	return cfg, nil
+}
+
+// readFile decodes a YAML configuration file over c. Unknown keys are errors.
+func (c *Config) readFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && err != io.EOF {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// Validate checks every setting and reports all problems at once
+func (c Config) Validate() error {
+	var problems Errors
+	add := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if n, err := strconv.Atoi(c.Port); err != nil || n < 1 || n > 65535 {
+		add("port: %q is not a TCP port", c.Port)
+	}
+	if c.DBPath == "" {
+		add("db_path: must be set")
+	}
+	if _, _, err := c.RefreshClock(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if _, err := c.Location(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	for _, w := range c.Worlds {
+		if strings.TrimSpace(w) == "" {
+			add("worlds: names must not be empty")
+			break
+		}
+	}
+
+	if c.DBBusyTimeout < 0 {
+		add("db_busy_timeout: must not be negative")
+	}
+	switch strings.ToUpper(c.DBSynchronous) {
+	case "OFF", "NORMAL", "FULL", "EXTRA":
+	default:
+		add("db_synchronous: must be OFF, NORMAL, FULL or EXTRA, not %q", c.DBSynchronous)
+	}
+	if c.DBReadConns < 1 {
+		add("db_read_conns: must be at least 1")
+	}
+
+	if strings.Count(c.SourceURL, "%s") != 1 {
+		add("source_url: must contain %%s once, for the world name")
+	} else if err := checkURL(strings.Replace(c.SourceURL, "%s", "antica", 1)); err != nil {
+		add("source_url: %v", err)
+	}
+	if c.SourceTimeout <= 0 {
+		add("source_timeout: must be positive")
+	}
+
+	if c.MetadataPath == "" {
+		add("metadata_path: must be set")
+	}
+	if c.MetadataWatchInterval < 0 {
+		add("metadata_watch_interval: must not be negative")
+	}
+
+	for i, hook := range c.DiscordWebhooks {
+		if err := checkURL(hook.URL); err != nil {
+			add("discord_webhooks[%d]: %v", i, err)
+		}
+	}
+	if c.DiscordHighChance < 0 || c.DiscordHighChance > 100 {
+		add("discord_high_chance: must be a percent between 0 and 100")
+	}
+
+	if c.WebhookMaxAttempts < 1 {
+		add("webhook_max_attempts: must be at least 1")
+	}
+	if c.WebhookBackoff <= 0 {
+		add("webhook_backoff: must be positive")
+	}
+	if c.WebhookMaxBackoff < c.WebhookBackoff {
+		add("webhook_max_backoff: must be at least webhook_backoff")
+	}
+	if c.WebhookTimeout <= 0 {
+		add("webhook_timeout: must be positive")
+	}
+
+	names, tokens := map[string]bool{}, map[string]bool{}
+	for i, t := range c.AdminTokens {
+		if t.Name == "" || t.Token == "" {
+			add("admin_tokens[%d]: needs a name and a token", i)
+			continue
+		}
+		if names[t.Name] {
+			add("admin_tokens[%d]: name %q is used twice", i, t.Name)
+		}
+		if tokens[t.Token] {
+			add("admin_tokens[%d]: token of %q is used twice", i, t.Name)
+		}
+		names[t.Name], tokens[t.Token] = true, true
+	}
+
+	if c.BackupDir == "" {
+		add("backup_dir: must be set")
+	}
+	if c.BackupInterval < 0 {
+		add("backup_interval: must not be negative")
+	}
+	if c.BackupKeep < 0 {
+		add("backup_keep: must not be negative")
+	}
+
+	if c.RetentionDays < 0 {
+		add("retention_days: must not be negative")
+	}
+	switch c.RetentionDownsample {
+	case "daily", "changes":
+	default:
+		add("retention_downsample: must be daily or changes, not %q", c.RetentionDownsample)
+	}
+	if c.MaintenanceInterval < 0 {
+		add("maintenance_interval: must not be negative")
+	}
+
+	switch strings.ToLower(c.LogLevel) {
+	case "debug", "info", "warn", "error":
+	default:
+		add("log_level: must be debug, info, warn or error, not %q", c.LogLevel)
+	}
+	switch strings.ToLower(c.LogFormat) {
+	case "text", "json":
+	default:
+		add("log_format: must be text or json, not %q", c.LogFormat)
+	}
+
+	if len(problems) > 0 {
+		return problems
+	}
+	return nil
+}
+
+// Location returns the scheduler's time zone
+func (c Config) Location() (*time.Location, error) {
+	tz, err := time.LoadLocation(c.TZ)
+	if err != nil {
+		return nil, fmt.Errorf("tz: unknown time zone %q", c.TZ)
+	}
+	return tz, nil
+}
+
+// RefreshClock returns the hour and minute of the daily refresh
+func (c Config) RefreshClock() (hour, minute int, err error) {
+	t, err := time.Parse("15:04", c.RefreshAt)
+	if err != nil {
+		return 0, 0, fmt.Errorf("refresh_at: %q is not a time like 09:30", c.RefreshAt)
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+// checkURL reports whether s is an absolute http or https URL
+func checkURL(s string) error {
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%q is not an http or https URL", s)
+	}
+	return nil
+}
+
+// Redacted returns a copy of c with the admin tokens and webhook URLs hidden,
+// for printing
+func (c Config) Redacted() Config {
+	const hidden = "REDACTED"
+	tokens := make([]AdminToken, len(c.AdminTokens))
+	for i, t := range c.AdminTokens {
+		tokens[i] = AdminToken{Name: t.Name, Token: hidden}
+	}
+	c.AdminTokens = tokens
+	// Discord webhook URLs carry their token in the path
+	hooks := make([]DiscordWebhook, len(c.DiscordWebhooks))
+	for i, h := range c.DiscordWebhooks {
+		hooks[i] = DiscordWebhook{World: h.World, URL: hidden}
+		if u, err := url.Parse(h.URL); err == nil && u.Host != "" {
+			hooks[i].URL = u.Scheme + "://" + u.Host + "/" + hidden
+		}
+	}
+	c.DiscordWebhooks = hooks
+	return c
+}
+
+// setting is one field of a Config, addressed by its key
+type setting struct {
+	key string
+	ptr any // Pointer to the field
+}
+
+func (s setting) env() string  { return strings.ToUpper(s.key) }
+func (s setting) flag() string { return strings.ReplaceAll(s.key, "_", "-") }
+
+// set parses v in the format of the environment variable into the field
+func (s setting) set(v string) error {
+	switch p := s.ptr.(type) {
+	case *string:
+		*p = v
+	case *int:
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", v)
+		}
+		*p = n
+	case *time.Duration:
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration like 30s or 24h", v)
+		}
+		*p = d
+	case *[]string:
+		*p = splitList(v)
+	case *[]DiscordWebhook:
+		*p = parseDiscordWebhooks(v)
+	case *[]AdminToken:
+		tokens, err := parseAdminTokens(v)
+		if err != nil {
+			return err
+		}
+		*p = tokens
+	default:
+		return fmt.Errorf("unsupported setting type %T", s.ptr)
+	}
+	return nil
+}
+
+// settings returns the fields of c that have a key
+func (c *Config) settings() []setting {
+	v := reflect.ValueOf(c).Elem()
+	var out []setting
+	for i := 0; i < v.NumField(); i++ {
+		key, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("yaml"), ",")
+		if key == "" || key == "-" {
+			continue
+		}
+		out = append(out, setting{key: key, ptr: v.Field(i).Addr().Interface()})
+	}
+	return out
+}
+
+// splitList splits a comma separated list, dropping empty entries
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
 }
 
-func Load() Config {
-	cfg := Config{
-		Port:      getenv("PORT", "8080"),
-		DBPath:    getenv("DB_PATH", "tibia-nemesis-api.db"),
-		RefreshAt: getenv("REFRESH_AT", "9:30"),
-		TZ:        getenv("TZ", "CET"),
+// parseDiscordWebhooks parses a comma separated list of "URL" or "World=URL" entries
+func parseDiscordWebhooks(s string) []DiscordWebhook {
+	var out []DiscordWebhook
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		hook := DiscordWebhook{URL: entry}
+		if i := strings.Index(entry, "="); i > 0 && !strings.Contains(entry[:i], "://") {
+			hook.World = strings.TrimSpace(entry[:i])
+			hook.URL = strings.TrimSpace(entry[i+1:])
+		}
+		out = append(out, hook)
 	}
-	return cfg
+	return out
 }
 
-func getenv(k, def string) string {
-	if v := os.Getenv(k); v != "" {
-		return v
+// parseAdminTokens parses a comma separated list of "name:token" entries
+func parseAdminTokens(s string) ([]AdminToken, error) {
+	var out []AdminToken
+	for _, entry := range splitList(s) {
+		name, token, ok := strings.Cut(entry, ":")
+		name, token = strings.TrimSpace(name), strings.TrimSpace(token)
+		if !ok || name == "" || token == "" {
+			return nil, errors.New("entries must look like name:token")
+		}
+		out = append(out, AdminToken{Name: name, Token: token})
 	}
-	return def
+	return out, nil
 }
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// clearEnv unsets every setting's environment variable for the test, so the
+// environment the tests run in doesn't leak into them
+func clearEnv(t *testing.T) {
+	t.Helper()
+	for _, s := range (&Config{}).settings() {
+		t.Setenv(s.env(), "")
+	}
+	t.Setenv("CONFIG_FILE", "")
+}
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	file := writeFile(t, "port: 9000\nrefresh_at: \"08:00\"\nworlds: [Antica, Secura]\n")
+	for _, tc := range []struct {
+		name      string
+		file      string
+		env       map[string]string
+		flags     Overrides
+		port      string
+		refreshAt string
+		worlds    int
+	}{
+		{name: "defaults", port: "8080", refreshAt: "9:30"},
+		{name: "file", file: file, port: "9000", refreshAt: "08:00", worlds: 2},
+		{name: "env over file", file: file, env: map[string]string{"PORT": "9100", "WORLDS": "Antica"}, port: "9100", refreshAt: "08:00", worlds: 1},
+		{name: "empty env is unset", file: file, env: map[string]string{"PORT": ""}, port: "9000", refreshAt: "08:00", worlds: 2},
+		{name: "flag over env", file: file, env: map[string]string{"PORT": "9100"}, flags: Overrides{"port": "9200", "refresh_at": "07:15"}, port: "9200", refreshAt: "07:15", worlds: 2},
+		{name: "CONFIG_FILE", env: map[string]string{"CONFIG_FILE": file}, port: "9000", refreshAt: "08:00", worlds: 2},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			clearEnv(t)
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+			cfg, err := Load(tc.file, tc.flags)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Port != tc.port || cfg.RefreshAt != tc.refreshAt || len(cfg.Worlds) != tc.worlds {
+				t.Errorf("port %s, refresh_at %s, %d worlds; want %s, %s, %d", cfg.Port, cfg.RefreshAt, len(cfg.Worlds), tc.port, tc.refreshAt, tc.worlds)
+			}
+		})
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		file  string
+		env   map[string]string
+		flags Overrides
+		want  []string // Every problem that must be reported
+	}{
+		{name: "unknown key", file: "port: 9000\nrefresh_time: \"08:00\"\n", want: []string{"field refresh_time not found"}},
+		{name: "bad tz and refresh_at", env: map[string]string{"TZ": "Mars/Olympus", "REFRESH_AT": "25:00"},
+			want: []string{`tz: unknown time zone "Mars/Olympus"`, `refresh_at: "25:00" is not a time`}},
+		{name: "refresh_at flag", flags: Overrides{"refresh_at": "9.30"}, want: []string{`refresh_at: "9.30" is not a time`}},
+		{name: "admin token without a name", env: map[string]string{"ADMIN_TOKENS": ":secret"}, want: []string{"ADMIN_TOKENS: entries must look like name:token"}},
+		{name: "admin token without a token", env: map[string]string{"ADMIN_TOKENS": "alice:s3cret,bob"}, want: []string{"ADMIN_TOKENS: entries must look like name:token"}},
+		{name: "admin tokens used twice", file: "admin_tokens:\n  - {name: alice, token: a}\n  - {name: alice, token: a}\n",
+			want: []string{`admin_tokens[1]: name "alice" is used twice`, `admin_tokens[1]: token of "alice" is used twice`}},
+		{name: "admin token incomplete in file", file: "admin_tokens:\n  - {name: alice}\n", want: []string{"admin_tokens[0]: needs a name and a token"}},
+		{name: "not a number", env: map[string]string{"DB_READ_CONNS": "many"}, want: []string{`DB_READ_CONNS: "many" is not a number`}},
+		{name: "webhook backoff", env: map[string]string{"WEBHOOK_BACKOFF": "2h"}, want: []string{"webhook_max_backoff: must be at least webhook_backoff"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			clearEnv(t)
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+			path := ""
+			if tc.file != "" {
+				path = writeFile(t, tc.file)
+			}
+			_, err := Load(path, tc.flags)
+			if err == nil {
+				t.Fatal("configuration was accepted")
+			}
+			for _, w := range tc.want {
+				if !strings.Contains(err.Error(), w) {
+					t.Errorf("error does not report %q:\n%v", w, err)
+				}
+			}
+		})
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := Defaults()
+	cfg.AdminTokens = []AdminToken{{Name: "alice", Token: "s3cret"}}
+	cfg.DiscordWebhooks = []DiscordWebhook{
+		{URL: "https://discord.com/api/webhooks/1/abcdef"},
+		{World: "Antica", URL: "not a url"},
+	}
+	r := cfg.Redacted()
+
+	if r.AdminTokens[0] != (AdminToken{Name: "alice", Token: "REDACTED"}) {
+		t.Errorf("admin token: %+v", r.AdminTokens[0])
+	}
+	if r.DiscordWebhooks[0].URL != "https://discord.com/REDACTED" {
+		t.Errorf("discord webhook: %s", r.DiscordWebhooks[0].URL)
+	}
+	if r.DiscordWebhooks[1] != (DiscordWebhook{World: "Antica", URL: "REDACTED"}) {
+		t.Errorf("unparsable discord webhook: %+v", r.DiscordWebhooks[1])
+	}
+	// The original is untouched
+	if cfg.AdminTokens[0].Token != "s3cret" || !strings.HasSuffix(cfg.DiscordWebhooks[0].URL, "/abcdef") {
+		t.Errorf("Redacted changed the configuration: %+v %+v", cfg.AdminTokens, cfg.DiscordWebhooks)
+	}
+}
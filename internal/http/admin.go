@@ -0,0 +1,199 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"tibia-nemesis-api/internal/models"
+)
+
+type actorKey struct{}
+
+// requireAdmin authenticates the bearer token of admin requests and records
+// the token's name as the actor for audit entries
+func (h *Handlers) requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		actor, err := h.svc.Authenticate(strings.TrimSpace(token))
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			writeError(w, r, err)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), actorKey{}, actor)))
+	})
+}
+
+// adminActor returns the name of the authenticated admin
+func adminActor(r *http.Request) string {
+	actor, _ := r.Context().Value(actorKey{}).(string)
+	return actor
+}
+
+func (h *Handlers) CreateBossMetadata(w http.ResponseWriter, r *http.Request) {
+	var meta models.BossMetadata
+	if err := decodeStrict(r, &meta); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	created, err := h.svc.CreateBossMetadata(r.Context(), adminActor(r), meta)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, created)
+}
+
+func (h *Handlers) UpdateBossMetadata(w http.ResponseWriter, r *http.Request) {
+	key := bossParam(r)
+	var meta models.BossMetadata
+	if err := decodeStrict(r, &meta); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if meta.Name == "" {
+		// The name may be left out when it does not change
+		cur, err := h.svc.BossMetadataEntry(r.Context(), key)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		meta.Name = cur.Name
+	}
+	updated, err := h.svc.UpdateBossMetadata(r.Context(), adminActor(r), key, meta)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, updated)
+}
+
+// PatchBossMetadata applies a JSON merge patch (RFC 7386) to a boss's metadata
+func (h *Handlers) PatchBossMetadata(w http.ResponseWriter, r *http.Request) {
+	key := bossParam(r)
+	cur, err := h.svc.BossMetadataEntry(r.Context(), key)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	var patch map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		writeError(w, r, badReq("invalid JSON body"))
+		return
+	}
+	var doc map[string]any
+	raw, _ := json.Marshal(cur)
+	json.Unmarshal(raw, &doc)
+	raw, _ = json.Marshal(mergePatch(doc, patch))
+
+	var meta models.BossMetadata
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&meta); err != nil {
+		writeError(w, r, badReq("invalid metadata: "+err.Error()))
+		return
+	}
+	updated, err := h.svc.UpdateBossMetadata(r.Context(), adminActor(r), key, meta)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, updated)
+}
+
+func (h *Handlers) DeleteBossMetadata(w http.ResponseWriter, r *http.Request) {
+	if err := h.svc.DeleteBossMetadata(r.Context(), adminActor(r), bossParam(r)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handlers) MetadataAudit(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit := 100
+	if s := q.Get("limit"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v > 0 {
+			limit = v
+		}
+	}
+	list, err := h.svc.MetadataAudit(r.Context(), q.Get("boss"), limit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (h *Handlers) ExportMetadata(w http.ResponseWriter, r *http.Request) {
+	data, err := h.svc.ExportBossMetadata(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Header().Set("Content-Disposition", `attachment; filename="bosses_metadata.yaml"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// decodeStrict decodes a JSON body, rejecting unknown fields like the metadata file does
+func decodeStrict(r *http.Request, v any) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return badReq("invalid JSON body: " + err.Error())
+	}
+	return nil
+}
+
+// mergePatch applies patch to doc: null removes a field, objects merge
+// recursively and anything else replaces the current value
+func mergePatch(doc, patch map[string]any) map[string]any {
+	if doc == nil {
+		doc = make(map[string]any)
+	}
+	for k, v := range patch {
+		switch pv := v.(type) {
+		case nil:
+			delete(doc, k)
+		case map[string]any:
+			cur, _ := doc[k].(map[string]any)
+			doc[k] = mergePatch(cur, pv)
+		default:
+			doc[k] = v
+		}
+	}
+	return doc
+}
+
+func (h *Handlers) CreateBackup(w http.ResponseWriter, r *http.Request) {
+	b, err := h.svc.Backup(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, b)
+}
+
+func (h *Handlers) ListBackups(w http.ResponseWriter, r *http.Request) {
+	list, err := h.svc.Backups(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (h *Handlers) Maintain(w http.ResponseWriter, r *http.Request) {
+	report, err := h.svc.Maintain(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
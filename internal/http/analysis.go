@@ -0,0 +1,86 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+func (h *Handlers) BossKills(w http.ResponseWriter, r *http.Request) {
+	world := r.URL.Query().Get("world")
+	if world == "" {
+		writeError(w, r, errMissing("world"))
+		return
+	}
+	limit := 25
+	if s := r.URL.Query().Get("limit"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v > 0 {
+			limit = v
+		}
+	}
+	list, err := h.svc.Kills(r.Context(), world, bossParam(r), limit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if format := responseFormat(r); format != formatJSON {
+		writeRows(w, r, format, killColumns, list, killRecord)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (h *Handlers) InclusionRangeAnalysis(w http.ResponseWriter, r *http.Request) {
+	contradicted, _ := strconv.ParseBool(r.URL.Query().Get("contradicted"))
+	a, err := h.svc.InclusionRangeAnalysis(r.Context(), contradicted)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, a)
+}
+
+func (h *Handlers) Forecast(w http.ResponseWriter, r *http.Request) {
+	world := r.URL.Query().Get("world")
+	if world == "" {
+		writeError(w, r, errMissing("world"))
+		return
+	}
+	days := 0
+	if s := r.URL.Query().Get("days"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil {
+			writeError(w, r, badReq("days must be an integer"))
+			return
+		}
+		days = v
+	}
+	f, err := h.svc.Forecast(r.Context(), world, bossParam(r), days)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, f)
+}
+
+func (h *Handlers) Calendar(w http.ResponseWriter, r *http.Request) {
+	world := r.URL.Query().Get("world")
+	if world == "" {
+		writeError(w, r, errMissing("world"))
+		return
+	}
+	var bosses []string
+	for _, b := range strings.Split(r.URL.Query().Get("bosses"), ",") {
+		if b = strings.TrimSpace(b); b != "" {
+			bosses = append(bosses, b)
+		}
+	}
+	data, err := h.svc.Calendar(r.Context(), world, bosses)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
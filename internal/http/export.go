@@ -0,0 +1,228 @@
+package http
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"tibia-nemesis-api/internal/models"
+)
+
+// Response formats of the list endpoints. JSON is the default.
+const (
+	formatJSON   = "json"
+	formatCSV    = "csv"
+	formatNDJSON = "ndjson"
+)
+
+var contentTypes = map[string]string{
+	formatJSON:   "application/json",
+	formatCSV:    "text/csv; charset=utf-8",
+	formatNDJSON: "application/x-ndjson",
+}
+
+// flushEvery is how many streamed rows are written between flushes
+const flushEvery = 500
+
+// responseFormat picks the format from the format parameter (validated
+// against the spec), falling back to the first supported type in Accept
+func responseFormat(r *http.Request) string {
+	if f := r.URL.Query().Get("format"); f != "" {
+		return f
+	}
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		media, _, _ := strings.Cut(part, ";")
+		switch strings.ToLower(strings.TrimSpace(media)) {
+		case "text/csv":
+			return formatCSV
+		case "application/x-ndjson", "application/ndjson":
+			return formatNDJSON
+		case "application/json":
+			return formatJSON
+		}
+	}
+	return formatJSON
+}
+
+// rowWriter streams records as a JSON array, CSV with a header row or NDJSON.
+// The response starts with the first row, so errors before it can still be
+// returned as a regular error response.
+type rowWriter struct {
+	w       http.ResponseWriter
+	format  string
+	columns []string
+	csv     *csv.Writer
+	enc     *json.Encoder
+	rows    int
+	started bool
+}
+
+func newRowWriter(w http.ResponseWriter, format string, columns []string) *rowWriter {
+	return &rowWriter{w: w, format: format, columns: columns}
+}
+
+func (rw *rowWriter) start() error {
+	rw.started = true
+	rw.w.Header().Set("Content-Type", contentTypes[rw.format])
+	rw.w.WriteHeader(http.StatusOK)
+	switch rw.format {
+	case formatCSV:
+		rw.csv = csv.NewWriter(rw.w)
+		return rw.csv.Write(rw.columns)
+	case formatJSON:
+		_, err := rw.w.Write([]byte("["))
+		rw.enc = json.NewEncoder(rw.w)
+		return err
+	}
+	rw.enc = json.NewEncoder(rw.w)
+	return nil
+}
+
+// Write writes v, or record when the format is CSV
+func (rw *rowWriter) Write(v any, record []string) error {
+	if !rw.started {
+		if err := rw.start(); err != nil {
+			return err
+		}
+	}
+	var err error
+	switch rw.format {
+	case formatCSV:
+		err = rw.csv.Write(record)
+	case formatJSON:
+		if rw.rows > 0 {
+			if _, err := rw.w.Write([]byte(",")); err != nil {
+				return err
+			}
+		}
+		err = rw.enc.Encode(v)
+	default:
+		err = rw.enc.Encode(v)
+	}
+	if err != nil {
+		return err
+	}
+	if rw.rows++; rw.rows%flushEvery == 0 {
+		rw.flush()
+	}
+	return nil
+}
+
+// Close ends the response, starting it first when no row was written
+func (rw *rowWriter) Close() error {
+	if !rw.started {
+		if err := rw.start(); err != nil {
+			return err
+		}
+	}
+	if rw.format == formatJSON {
+		if _, err := rw.w.Write([]byte("]\n")); err != nil {
+			return err
+		}
+	}
+	rw.flush()
+	if rw.csv != nil {
+		return rw.csv.Error()
+	}
+	return nil
+}
+
+func (rw *rowWriter) flush() {
+	if rw.csv != nil {
+		rw.csv.Flush()
+	}
+	if f, ok := rw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// writeRows writes a list as CSV or NDJSON; JSON responses go through writeJSON
+func writeRows[T any](w http.ResponseWriter, r *http.Request, format string, columns []string, list []T, record func(T) []string) {
+	rw := newRowWriter(w, format, columns)
+	for _, v := range list {
+		if err := rw.Write(v, record(v)); err != nil {
+			slog.ErrorContext(r.Context(), "writing response failed", "err", err)
+			return
+		}
+	}
+	if err := rw.Close(); err != nil {
+		slog.ErrorContext(r.Context(), "writing response failed", "err", err)
+	}
+}
+
+var observationColumns = []string{"world", "name", "percent", "days_since_kill", "is_no_chance", "updated_at"}
+
+func observationRecord(o models.SpawnChance) []string {
+	return []string{o.World, o.Name, csvInt(o.Percent), csvInt(o.DaysSinceKill), strconv.FormatBool(o.IsNoChance), o.UpdatedAt.UTC().Format(time.RFC3339)}
+}
+
+var killColumns = []string{"world", "name", "killed_on", "observed_at"}
+
+func killRecord(k models.Kill) []string {
+	return []string{k.World, k.Name, k.KilledOn, k.ObservedAt.UTC().Format(time.RFC3339)}
+}
+
+// bossRow is a boss with its world, so rows of several worlds can be combined
+type bossRow struct {
+	World string `json:"world"`
+	models.BossInfo
+}
+
+var bossColumns = []string{"world", "id", "name", "percent", "days_since_kill", "spawnable", "estimated_percent", "confidence",
+	"category", "location", "multi_spawn", "event_only", "wiki_url", "note"}
+
+func bossRecord(b bossRow) []string {
+	location := ""
+	if b.Location != nil {
+		location = b.Location.Description
+	}
+	return []string{b.World, b.ID, b.Name, csvInt(b.Percent), csvInt(b.DaysSinceKill), strconv.FormatBool(b.Spawnable),
+		csvInt(b.EstimatedPercent), b.Confidence, b.Category, location, strconv.FormatBool(b.MultiSpawn),
+		strconv.FormatBool(b.EventOnly), b.WikiURL, b.Note}
+}
+
+// csvInt renders a nullable number, empty when unknown
+func csvInt(v *int) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.Itoa(*v)
+}
+
+// Export streams stored observations in any of the list formats
+func (h *Handlers) Export(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var from, to time.Time
+	if s := q.Get("from"); s != "" {
+		from, _ = time.Parse("2006-01-02", s)
+	}
+	if s := q.Get("to"); s != "" {
+		// to is inclusive: the whole day is exported
+		to, _ = time.Parse("2006-01-02", s)
+		to = to.AddDate(0, 0, 1)
+	}
+	format := responseFormat(r)
+	rw := newRowWriter(w, format, observationColumns)
+	if format == formatCSV {
+		w.Header().Set("Content-Disposition", `attachment; filename="observations.csv"`)
+	}
+	err := h.svc.Export(r.Context(), q.Get("world"), from, to, func(o models.SpawnChance) error {
+		return rw.Write(o, observationRecord(o))
+	})
+	if err == nil {
+		err = rw.Close()
+	}
+	if err != nil {
+		if !rw.started {
+			w.Header().Del("Content-Disposition")
+			writeError(w, r, err)
+			return
+		}
+		// The status line is gone already; the truncated body is all the client sees
+		slog.ErrorContext(r.Context(), "export aborted", "rows", rw.rows, "err", err)
+	}
+}
@@ -2,12 +2,15 @@ package http
 
 import (
 	"encoding/json"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"strconv"
 
 	"tibia-nemesis-api/internal/service"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 )
 
 type Handlers struct {
@@ -18,15 +21,16 @@ func NewHandlers(svc *service.Service) *Handlers { return &Handlers{svc: svc} }
 
 func (h *Handlers) Status(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]any{
-		"status":  "ok",
-		"version": "v0.1.0",
+		"status":   "ok",
+		"version":  "v0.1.0",
+		"metadata": h.svc.MetadataStatus(),
 	})
 }
 
 func (h *Handlers) Worlds(w http.ResponseWriter, r *http.Request) {
 	worlds, err := h.svc.Worlds(r.Context())
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err)
+		writeError(w, r, err)
 		return
 	}
 	writeJSON(w, http.StatusOK, worlds)
@@ -35,24 +39,46 @@ func (h *Handlers) Worlds(w http.ResponseWriter, r *http.Request) {
 func (h *Handlers) Bosses(w http.ResponseWriter, r *http.Request) {
 	world := r.URL.Query().Get("world")
 	if world == "" {
-		writeError(w, http.StatusBadRequest, errMissing("world"))
+		writeError(w, r, errMissing("world"))
 		return
 	}
 	response, err := h.svc.Bosses(r.Context(), world)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err)
+		writeError(w, r, err)
+		return
+	}
+	if format := responseFormat(r); format != formatJSON {
+		rows := make([]bossRow, len(response.Bosses))
+		for i, b := range response.Bosses {
+			rows[i] = bossRow{World: response.World, BossInfo: b}
+		}
+		writeRows(w, r, format, bossColumns, rows, bossRecord)
 		return
 	}
 	writeJSON(w, http.StatusOK, response)
 }
 
+func (h *Handlers) Boss(w http.ResponseWriter, r *http.Request) {
+	world := r.URL.Query().Get("world")
+	if world == "" {
+		writeError(w, r, errMissing("world"))
+		return
+	}
+	boss, err := h.svc.Boss(r.Context(), world, bossParam(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, boss)
+}
+
 func (h *Handlers) BossHistory(w http.ResponseWriter, r *http.Request) {
 	world := r.URL.Query().Get("world")
 	if world == "" {
-		writeError(w, http.StatusBadRequest, errMissing("world"))
+		writeError(w, r, errMissing("world"))
 		return
 	}
-	name := chi.URLParam(r, "name")
+	name := bossParam(r)
 	limit := 25
 	if s := r.URL.Query().Get("limit"); s != "" {
 		if v, err := strconv.Atoi(s); err == nil && v > 0 {
@@ -61,33 +87,129 @@ func (h *Handlers) BossHistory(w http.ResponseWriter, r *http.Request) {
 	}
 	list, err := h.svc.BossHistory(r.Context(), world, name, limit)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err)
+		writeError(w, r, err)
+		return
+	}
+	if format := responseFormat(r); format != formatJSON {
+		writeRows(w, r, format, observationColumns, list, observationRecord)
 		return
 	}
 	writeJSON(w, http.StatusOK, list)
 }
 
+func (h *Handlers) BossMetadata(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.svc.BossMetadata(r.Context()))
+}
+
+func (h *Handlers) WorldGroups(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.svc.WorldGroups(r.Context()))
+}
+
+func (h *Handlers) ReloadMetadata(w http.ResponseWriter, r *http.Request) {
+	status, err := h.svc.ReloadMetadata(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
 func (h *Handlers) Refresh(w http.ResponseWriter, r *http.Request) {
 	world := r.URL.Query().Get("world")
 	if world == "" {
-		writeError(w, http.StatusBadRequest, errMissing("world"))
+		writeError(w, r, errMissing("world"))
 		return
 	}
+	// Worlds that are not registered yet are looked up at the data source
 	if err := h.svc.RefreshWorld(r.Context(), world); err != nil {
-		writeError(w, http.StatusInternalServerError, err)
+		writeError(w, r, err)
+		return
+	}
+	world, err := h.svc.CanonicalWorld(world)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "world": world})
 }
 
+// bossParam returns the unescaped {name} path parameter
+func bossParam(r *http.Request) string {
+	name := chi.URLParam(r, "name")
+	if v, err := url.PathUnescape(name); err == nil {
+		return v
+	}
+	return name
+}
+
 func writeJSON(w http.ResponseWriter, status int, v any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	_ = json.NewEncoder(w).Encode(v)
 }
 
-func writeError(w http.ResponseWriter, status int, err error) {
-	writeJSON(w, status, map[string]string{"error": err.Error()})
+// errorBody is the JSON body of every error response
+type errorBody struct {
+	Code      service.Code   `json:"code"`
+	Message   string         `json:"message"`
+	RequestID string         `json:"request_id,omitempty"`
+	Details   map[string]any `json:"details,omitempty"`
+}
+
+var statusByCode = map[service.Code]int{
+	service.CodeValidation:          http.StatusBadRequest,
+	service.CodeNotFound:            http.StatusNotFound,
+	service.CodeUnknownWorld:        http.StatusNotFound,
+	service.CodeUnknownBoss:         http.StatusNotFound,
+	service.CodeConflict:            http.StatusConflict,
+	service.CodeUnauthorized:        http.StatusUnauthorized,
+	service.CodeUpstreamUnavailable: http.StatusBadGateway,
+	service.CodeRateLimited:         http.StatusTooManyRequests,
+	service.CodeInternal:            http.StatusInternalServerError,
+}
+
+// writeError maps err to its status code and error body. Causes of internal
+// errors are logged, never returned.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var e *service.Error
+	if br, ok := err.(badReq); ok {
+		e = &service.Error{Code: service.CodeValidation, Message: string(br)}
+	} else {
+		e = service.AsError(err)
+	}
+	status, ok := statusByCode[e.Code]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	reqID := middleware.GetReqID(r.Context())
+	if status >= http.StatusInternalServerError || e.Err != nil {
+		slog.ErrorContext(r.Context(), "request failed", "method", r.Method, "path", r.URL.Path, "err", e)
+	}
+	if v, ok := e.Details["retry_after"].(string); ok {
+		w.Header().Set("Retry-After", v)
+	}
+	writeJSON(w, status, errorBody{
+		Code:      e.Code,
+		Message:   e.Message,
+		RequestID: reqID,
+		Details:   e.Details,
+	})
+}
+
+func notFoundHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusNotFound, errorBody{
+		Code:      service.CodeNotFound,
+		Message:   "route not found",
+		RequestID: middleware.GetReqID(r.Context()),
+	})
+}
+
+func methodNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusMethodNotAllowed, errorBody{
+		Code:      "method_not_allowed",
+		Message:   "method not allowed",
+		RequestID: middleware.GetReqID(r.Context()),
+	})
 }
 
 type badReq string
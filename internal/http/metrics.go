@@ -0,0 +1,58 @@
+package http
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"tibia-nemesis-api/internal/metrics"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+var (
+	requests        = metrics.NewCounter("tibia_nemesis_http_requests_total", "HTTP requests by method, route and status", "method", "route", "status")
+	requestDuration = metrics.NewHistogram("tibia_nemesis_http_request_duration_seconds", "Duration of HTTP requests by method and route", metrics.DefaultBuckets, "method", "route")
+)
+
+// instrument counts requests and their latency by route pattern, so paths
+// with different world or boss names share one series. Requests answered
+// before routing, like those the request validator rejects, are matched
+// against routes to find their pattern.
+func instrument(routes chi.Routes) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			route := chi.RouteContext(r.Context()).RoutePattern()
+			if route == "" {
+				if rctx := chi.NewRouteContext(); routes.Match(rctx, r.Method, r.URL.Path) {
+					route = rctx.RoutePattern()
+				}
+			}
+			if route == "" {
+				route = "unmatched"
+			}
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			requests.Inc(r.Method, route, strconv.Itoa(status))
+			requestDuration.Observe(time.Since(start).Seconds(), r.Method, route)
+		})
+	}
+}
+
+// Metrics serves the metrics in the Prometheus text format
+func (h *Handlers) Metrics(w http.ResponseWriter, r *http.Request) {
+	if err := h.svc.CollectMetrics(r.Context()); err != nil {
+		slog.ErrorContext(r.Context(), "metrics: collect failed", "err", err)
+	}
+	w.Header().Set("Content-Type", metrics.ContentType)
+	w.WriteHeader(http.StatusOK)
+	_, _ = metrics.Default.WriteTo(w)
+}
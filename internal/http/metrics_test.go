@@ -0,0 +1,50 @@
+package http
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// requestCount returns the value of one series of the requests counter
+func requestCount(t *testing.T, h http.Handler, method, route, status string) float64 {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	series := `tibia_nemesis_http_requests_total{method="` + method + `",route="` + route + `",status="` + status + `"} `
+	sc := bufio.NewScanner(rec.Body)
+	for sc.Scan() {
+		if v, ok := strings.CutPrefix(sc.Text(), series); ok {
+			n, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return n
+		}
+	}
+	return 0
+}
+
+func TestRejectedRequestsCountedByRoute(t *testing.T) {
+	r := newTestRouter(t)
+	for _, tc := range []struct {
+		path, route, status string
+	}{
+		{"/api/v1/bosses", "/api/v1/bosses", "400"}, // Rejected by the request validator
+		{"/api/v1/boss/Furyosa/history", "/api/v1/boss/{name}/history", "400"},
+		{"/api/v1/nowhere", "unmatched", "404"},
+	} {
+		before := requestCount(t, r, "GET", tc.route, tc.status)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", tc.path, nil))
+		if got := strconv.Itoa(rec.Code); got != tc.status {
+			t.Fatalf("GET %s: status %s, want %s", tc.path, got, tc.status)
+		}
+		if after := requestCount(t, r, "GET", tc.route, tc.status); after != before+1 {
+			t.Errorf("GET %s: counted %v times as %s, want once", tc.path, after-before, tc.route)
+		}
+	}
+}
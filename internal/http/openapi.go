@@ -0,0 +1,347 @@
+package http
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+//go:embed openapi.json
+var openAPIDoc []byte
+
+// Minimal OpenAPI 3 model: just enough to validate requests against the document
+type openAPISpec struct {
+	Paths      map[string]openAPIPathItem `json:"paths"`
+	Components struct {
+		Parameters map[string]openAPIParameter `json:"parameters"`
+		Schemas    map[string]*openAPISchema   `json:"schemas"`
+	} `json:"components"`
+}
+
+type openAPIPathItem struct {
+	Parameters []openAPIParameter `json:"parameters"`
+	Get        *openAPIOperation  `json:"get"`
+	Post       *openAPIOperation  `json:"post"`
+	Put        *openAPIOperation  `json:"put"`
+	Patch      *openAPIOperation  `json:"patch"`
+	Delete     *openAPIOperation  `json:"delete"`
+}
+
+type openAPIOperation struct {
+	Parameters  []openAPIParameter `json:"parameters"`
+	RequestBody *struct {
+		Required bool `json:"required"`
+		Content  map[string]struct {
+			Schema *openAPISchema `json:"schema"`
+		} `json:"content"`
+	} `json:"requestBody"`
+}
+
+type openAPIParameter struct {
+	Ref      string         `json:"$ref"`
+	Name     string         `json:"name"`
+	In       string         `json:"in"`
+	Required bool           `json:"required"`
+	Schema   *openAPISchema `json:"schema"`
+}
+
+type openAPISchema struct {
+	Ref        string                    `json:"$ref"`
+	Type       string                    `json:"type"`
+	Format     string                    `json:"format"`
+	Enum       []string                  `json:"enum"`
+	Minimum    *float64                  `json:"minimum"`
+	Maximum    *float64                  `json:"maximum"`
+	MinLength  int                       `json:"minLength"`
+	Required   []string                  `json:"required"`
+	Properties map[string]*openAPISchema `json:"properties"`
+	Items      *openAPISchema            `json:"items"`
+}
+
+var spec = mustParseSpec(openAPIDoc)
+
+func mustParseSpec(doc []byte) *openAPISpec {
+	var s openAPISpec
+	if err := json.Unmarshal(doc, &s); err != nil {
+		panic(fmt.Sprintf("openapi.json: %v", err))
+	}
+	return &s
+}
+
+func (h *Handlers) OpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(openAPIDoc)
+}
+
+func (p openAPIPathItem) operation(method string) *openAPIOperation {
+	switch method {
+	case http.MethodGet:
+		return p.Get
+	case http.MethodPost:
+		return p.Post
+	case http.MethodPut:
+		return p.Put
+	case http.MethodPatch:
+		return p.Patch
+	case http.MethodDelete:
+		return p.Delete
+	}
+	return nil
+}
+
+// MissingFromSpec lists "METHOD /pattern" for every registered route the document does not describe
+func MissingFromSpec(routes chi.Routes) []string {
+	var missing []string
+	_ = chi.Walk(routes, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		item, ok := spec.Paths[route]
+		if !ok || item.operation(method) == nil {
+			missing = append(missing, method+" "+route)
+		}
+		return nil
+	})
+	sort.Strings(missing)
+	return missing
+}
+
+func (s *openAPISpec) param(p openAPIParameter) openAPIParameter {
+	if name, ok := strings.CutPrefix(p.Ref, "#/components/parameters/"); ok {
+		return s.Components.Parameters[name]
+	}
+	return p
+}
+
+func (s *openAPISpec) schema(sc *openAPISchema) *openAPISchema {
+	if sc == nil {
+		return nil
+	}
+	if name, ok := strings.CutPrefix(sc.Ref, "#/components/schemas/"); ok {
+		return s.Components.Schemas[name]
+	}
+	return sc
+}
+
+// validateRequests rejects requests whose parameters or JSON body do not match the
+// operation declared in the OpenAPI document. Routes missing from the document pass through.
+func validateRequests(routes chi.Routes) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rctx := chi.NewRouteContext()
+			if !routes.Match(rctx, r.Method, r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			item, ok := spec.Paths[rctx.RoutePattern()]
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			op := item.operation(r.Method)
+			if op == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if err := validateParams(r, rctx, append(item.Parameters, op.Parameters...)); err != nil {
+				writeError(w, r, err)
+				return
+			}
+			if err := validateBody(r, op); err != nil {
+				writeError(w, r, err)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func validateParams(r *http.Request, rctx *chi.Context, params []openAPIParameter) error {
+	query := r.URL.Query()
+	for _, p := range params {
+		p = spec.param(p)
+		var value string
+		var present bool
+		switch p.In {
+		case "query":
+			present = query.Has(p.Name)
+			value = query.Get(p.Name)
+		case "path":
+			value = rctx.URLParam(p.Name)
+			present = value != ""
+		default:
+			continue
+		}
+		if !present || value == "" {
+			if p.Required {
+				return errMissing(p.Name)
+			}
+			continue
+		}
+		if err := validateValue(spec.schema(p.Schema), value); err != nil {
+			return badReq(fmt.Sprintf("invalid parameter %s: %v", p.Name, err))
+		}
+	}
+	return nil
+}
+
+// validateValue checks a raw string parameter against a scalar schema
+func validateValue(sc *openAPISchema, value string) error {
+	if sc == nil {
+		return nil
+	}
+	switch sc.Type {
+	case "integer":
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("must be an integer")
+		}
+		return checkRange(sc, float64(v))
+	case "boolean":
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("must be a boolean")
+		}
+	case "string":
+		if len(value) < sc.MinLength {
+			return fmt.Errorf("must be at least %d characters", sc.MinLength)
+		}
+		return checkString(sc, value)
+	}
+	return nil
+}
+
+func checkRange(sc *openAPISchema, v float64) error {
+	if sc.Minimum != nil && v < *sc.Minimum {
+		return fmt.Errorf("must be >= %v", *sc.Minimum)
+	}
+	if sc.Maximum != nil && v > *sc.Maximum {
+		return fmt.Errorf("must be <= %v", *sc.Maximum)
+	}
+	return nil
+}
+
+func checkString(sc *openAPISchema, v string) error {
+	if len(sc.Enum) > 0 {
+		for _, e := range sc.Enum {
+			if e == v {
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of %s", strings.Join(sc.Enum, ", "))
+	}
+	switch sc.Format {
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, v); err != nil {
+			return fmt.Errorf("must be an RFC 3339 timestamp")
+		}
+	case "date":
+		if _, err := time.Parse("2006-01-02", v); err != nil {
+			return fmt.Errorf("must be a YYYY-MM-DD date")
+		}
+	}
+	return nil
+}
+
+// validateBody checks a JSON body's required properties and top-level property types.
+// The body is restored so handlers can decode it again.
+func validateBody(r *http.Request, op *openAPIOperation) error {
+	if op.RequestBody == nil {
+		return nil
+	}
+	media, ok := op.RequestBody.Content["application/json"]
+	if !ok {
+		return nil
+	}
+	raw, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		return badReq("unreadable body")
+	}
+	r.Body = io.NopCloser(bytes.NewReader(raw))
+	if len(bytes.TrimSpace(raw)) == 0 {
+		if op.RequestBody.Required {
+			return badReq("request body required")
+		}
+		return nil
+	}
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &body); err != nil {
+		return badReq("body must be a JSON object")
+	}
+	sc := spec.schema(media.Schema)
+	if sc == nil {
+		return nil
+	}
+	for _, name := range sc.Required {
+		if _, ok := body[name]; !ok {
+			return badReq("missing field: " + name)
+		}
+	}
+	for name, v := range body {
+		prop := spec.schema(sc.Properties[name])
+		if prop == nil || string(v) == "null" {
+			continue
+		}
+		if err := validateJSON(prop, v); err != nil {
+			return badReq(fmt.Sprintf("invalid field %s: %v", name, err))
+		}
+	}
+	return nil
+}
+
+func validateJSON(sc *openAPISchema, raw json.RawMessage) error {
+	switch sc.Type {
+	case "string":
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return fmt.Errorf("must be a string")
+		}
+		if len(s) < sc.MinLength {
+			return fmt.Errorf("must be at least %d characters", sc.MinLength)
+		}
+		return checkString(sc, s)
+	case "integer":
+		var n float64
+		if err := json.Unmarshal(raw, &n); err != nil || n != float64(int64(n)) {
+			return fmt.Errorf("must be an integer")
+		}
+		return checkRange(sc, n)
+	case "number":
+		var n float64
+		if err := json.Unmarshal(raw, &n); err != nil {
+			return fmt.Errorf("must be a number")
+		}
+		return checkRange(sc, n)
+	case "boolean":
+		var b bool
+		if err := json.Unmarshal(raw, &b); err != nil {
+			return fmt.Errorf("must be a boolean")
+		}
+	case "array":
+		var items []json.RawMessage
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return fmt.Errorf("must be an array")
+		}
+		item := spec.schema(sc.Items)
+		if item == nil {
+			return nil
+		}
+		for i, v := range items {
+			if err := validateJSON(item, v); err != nil {
+				return fmt.Errorf("item %d %v", i, err)
+			}
+		}
+	case "object":
+		var m map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &m); err != nil {
+			return fmt.Errorf("must be an object")
+		}
+	}
+	return nil
+}
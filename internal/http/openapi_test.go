@@ -0,0 +1,412 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"tibia-nemesis-api/internal/config"
+	"tibia-nemesis-api/internal/models"
+	"tibia-nemesis-api/internal/service"
+	"tibia-nemesis-api/internal/store"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const testAdminToken = "test-admin-token"
+
+// fakeScraper returns the same page for every world
+type fakeScraper struct{}
+
+func (fakeScraper) Fetch(ctx context.Context, world string) ([]models.SpawnChance, error) {
+	return []models.SpawnChance{
+		{Name: "Furyosa", Percent: intp(20), DaysSinceKill: intp(16)},
+		{Name: "Barbaria", DaysSinceKill: intp(4)},
+		{Name: "Albino Dragon", IsNoChance: true},
+	}, nil
+}
+
+func intp(v int) *int { return &v }
+
+// newTestRouter serves a temporary database holding a month of Antica
+// scrapes with a few kills
+func newTestRouter(t *testing.T) chi.Router {
+	t.Helper()
+	dir := t.TempDir()
+	cfg := config.Defaults()
+	cfg.DBPath = filepath.Join(dir, "api.db")
+	cfg.MetadataPath = filepath.Join("..", "..", "bosses_metadata.yaml")
+	cfg.MetadataWatchInterval = 0
+	cfg.BackupDir = filepath.Join(dir, "backups")
+	cfg.AdminTokens = []config.AdminToken{{Name: "test", Token: testAdminToken}}
+
+	st, err := store.NewSQLite(cfg.DBPath, store.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { st.Close() })
+	start := time.Now().UTC().AddDate(0, 0, -30)
+	for day := 0; day < 30; day++ {
+		err := st.UpsertSpawnChances("Antica", []models.SpawnChance{
+			{Name: "Furyosa", Percent: intp(5 + day/2), DaysSinceKill: intp((day + 3) % 12), UpdatedAt: start.AddDate(0, 0, day)},
+			{Name: "Barbaria", DaysSinceKill: intp((day + 5) % 9), UpdatedAt: start.AddDate(0, 0, day)},
+			{Name: "Albino Dragon", IsNoChance: true, UpdatedAt: start.AddDate(0, 0, day)},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	svc, err := service.New(st, fakeScraper{}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewRouter(svc).(chi.Router)
+}
+
+func TestRoutesMatchOpenAPI(t *testing.T) {
+	r := newTestRouter(t)
+	registered := make(map[string]bool)
+	err := chi.Walk(r, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		registered[method+" "+route] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	documented := make(map[string]bool)
+	for _, op := range documentedOperations() {
+		documented[op] = true
+		if !registered[op] {
+			t.Errorf("%s is in openapi.json but not routed", op)
+		}
+	}
+	for op := range registered {
+		if !documented[op] {
+			t.Errorf("%s is routed but missing from openapi.json", op)
+		}
+	}
+}
+
+// documentedOperations lists "METHOD /path" for every operation in openapi.json
+func documentedOperations() []string {
+	var ops []string
+	for path, item := range spec.Paths {
+		for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+			if item.operation(method) != nil {
+				ops = append(ops, method+" "+path)
+			}
+		}
+	}
+	sort.Strings(ops)
+	return ops
+}
+
+func TestResponsesMatchOpenAPI(t *testing.T) {
+	r := newTestRouter(t)
+	doc := newSchemaDoc(t)
+
+	// In order: later requests use what earlier ones created
+	requests := []struct {
+		method, target, body string
+		admin                bool
+		status               int
+	}{
+		{"GET", "/api/v1/status", "", false, 200},
+		{"GET", "/metrics", "", false, 200},
+		{"GET", "/api/v1/openapi.json", "", false, 200},
+		{"GET", "/api/v1/worlds", "", false, 200},
+		{"GET", "/api/v1/bosses?world=Antica", "", false, 200},
+		{"GET", "/api/v1/bosses?world=Antica&format=csv", "", false, 200},
+		{"GET", "/api/v1/bosses?world=Antica&format=ndjson", "", false, 200},
+		{"GET", "/api/v1/bosses", "", false, 400},
+		{"GET", "/api/v1/bosses?world=Nowhere", "", false, 404},
+		{"GET", "/api/v1/bosses?world=Secura", "", false, 200},
+		{"GET", "/api/v1/boss/Furyosa?world=Antica", "", false, 200},
+		{"GET", "/api/v1/boss/Nobody?world=Antica", "", false, 404},
+		{"GET", "/api/v1/boss/Furyosa/history?world=Antica&limit=5", "", false, 200},
+		{"GET", "/api/v1/boss/Furyosa/history?world=Antica&limit=0", "", false, 400},
+		{"GET", "/api/v1/boss/Furyosa/kills?world=Antica", "", false, 200},
+		{"GET", "/api/v1/boss/Furyosa/forecast?world=Antica&days=3", "", false, 200},
+		{"GET", "/api/v1/analysis/inclusion-ranges", "", false, 200},
+		{"GET", "/api/v1/calendar.ics?world=Antica", "", false, 200},
+		{"GET", "/api/v1/export?world=Antica", "", false, 200},
+		{"GET", "/api/v1/export?world=Antica&format=ndjson", "", false, 200},
+		{"GET", "/api/v1/metadata/bosses", "", false, 200},
+		{"GET", "/api/v1/metadata/world-groups", "", false, 200},
+		{"POST", "/api/v1/refresh?world=Antica", "", false, 200},
+
+		{"POST", "/api/v1/admin/metadata/reload", "", false, 401},
+		{"POST", "/api/v1/admin/metadata/reload", "", true, 200},
+		{"POST", "/api/v1/admin/metadata/bosses", `{"name": "Test Boss", "category": "Test"}`, true, 201},
+		{"POST", "/api/v1/admin/metadata/bosses", `{"name": "Test Boss"}`, true, 409},
+		{"PUT", "/api/v1/admin/metadata/bosses/Test%20Boss", `{"name": "Test Boss", "note": "Replaced"}`, true, 200},
+		{"PATCH", "/api/v1/admin/metadata/bosses/Test%20Boss", `{"inclusion_range": {"min_days": 3, "max_days": 9}}`, true, 200},
+		{"DELETE", "/api/v1/admin/metadata/bosses/Test%20Boss", "", true, 204},
+		{"GET", "/api/v1/admin/metadata/audit", "", true, 200},
+		{"GET", "/api/v1/admin/metadata/export", "", true, 200},
+		{"POST", "/api/v1/admin/backups", "", true, 201},
+		{"GET", "/api/v1/admin/backups", "", true, 200},
+		{"POST", "/api/v1/admin/maintenance", "", true, 200},
+
+		{"GET", "/api/v1/webhooks", "", true, 200},
+		{"POST", "/api/v1/webhooks", `{"url": "http://127.0.0.1:1/hook", "events": ["boss.threshold"]}`, false, 401},
+		{"POST", "/api/v1/webhooks", `{"url": "http://127.0.0.1:1/hook", "events": ["boss.threshold"], "threshold_percent": 10}`, true, 201},
+		{"POST", "/api/v1/webhooks/1/test", "", false, 401},
+		{"POST", "/api/v1/webhooks", `{"events": "all"}`, false, 401}, // Authenticated before validated
+		{"POST", "/api/v1/webhooks", `{"url": "not a url", "events": ["boss.threshold"]}`, true, 400},
+		{"GET", "/api/v1/webhooks", "", true, 200},
+		{"GET", "/api/v1/webhooks/1", "", true, 200},
+		{"PUT", "/api/v1/webhooks/1", `{"url": "http://127.0.0.1:1/hook", "events": ["refresh.completed"]}`, true, 200},
+		{"POST", "/api/v1/webhooks/1/test", "", true, 202},
+		{"GET", "/api/v1/webhooks/1/deliveries", "", true, 200},
+		{"DELETE", "/api/v1/webhooks/1", "", true, 204},
+		{"GET", "/api/v1/webhooks/1", "", true, 404},
+
+		{"GET", "/api/v1/subscribers/alice/watches", "", true, 200},
+		{"GET", "/api/v1/subscribers/alice/watches", "", false, 401},
+		{"POST", "/api/v1/subscribers/alice/watches", `{"condition": "sometimes"}`, false, 401},
+		{"POST", "/api/v1/subscribers/alice/watches", `{"world": "Antica", "boss": "Furyosa", "condition": "percent_gte", "value": 10}`, true, 201},
+		{"POST", "/api/v1/subscribers/alice/watches", `{"world": "Antica", "boss": "Furyosa", "condition": "sometimes"}`, true, 400},
+		{"POST", "/api/v1/subscribers/alice/watches", `{"world": "Antica", "boss": "Nobody", "condition": "spawnable"}`, true, 404},
+		{"GET", "/api/v1/subscribers/alice/watches", "", true, 200},
+		{"GET", "/api/v1/alerts?subscriber=alice", "", true, 200},
+		{"GET", "/api/v1/alerts", "", false, 401},
+		{"DELETE", "/api/v1/subscribers/alice/watches/1", "", true, 204},
+	}
+
+	exercised := make(map[string]bool)
+	for _, tc := range requests {
+		name := tc.method + " " + tc.target
+		req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+		if tc.body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if tc.admin {
+			req.Header.Set("Authorization", "Bearer "+testAdminToken)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if rec.Code != tc.status {
+			t.Errorf("%s: status %d, want %d: %s", name, rec.Code, tc.status, rec.Body.String())
+			continue
+		}
+
+		rctx := chi.NewRouteContext()
+		u, _ := url.Parse(tc.target)
+		if !r.Match(rctx, tc.method, u.Path) {
+			t.Errorf("%s: no route", name)
+			continue
+		}
+		op := tc.method + " " + rctx.RoutePattern()
+		exercised[op] = true
+		for _, problem := range doc.checkResponse(tc.method, rctx.RoutePattern(), rec) {
+			t.Errorf("%s: %s", name, problem)
+		}
+	}
+	for _, op := range documentedOperations() {
+		if !exercised[op] {
+			t.Errorf("%s is not exercised", op)
+		}
+	}
+}
+
+// schemaDoc is openapi.json decoded generically, for checking responses
+// against every schema keyword the document uses
+type schemaDoc map[string]any
+
+func newSchemaDoc(t *testing.T) schemaDoc {
+	t.Helper()
+	var doc schemaDoc
+	if err := json.Unmarshal(openAPIDoc, &doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+// resolve follows a local $ref
+func (d schemaDoc) resolve(node map[string]any) map[string]any {
+	for {
+		ref, ok := node["$ref"].(string)
+		if !ok {
+			return node
+		}
+		var cur any = map[string]any(d)
+		for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			cur = cur.(map[string]any)[part]
+		}
+		node = cur.(map[string]any)
+	}
+}
+
+func (d schemaDoc) get(node map[string]any, keys ...string) map[string]any {
+	for _, k := range keys {
+		next, ok := node[k].(map[string]any)
+		if !ok {
+			return nil
+		}
+		node = d.resolve(next)
+	}
+	return node
+}
+
+// checkResponse returns what is wrong with rec as a response of the operation
+func (d schemaDoc) checkResponse(method, pattern string, rec *httptest.ResponseRecorder) []string {
+	op := d.get(map[string]any(d), "paths", pattern, strings.ToLower(method))
+	resp := d.get(op, "responses", fmt.Sprint(rec.Code))
+	if resp == nil {
+		return []string{fmt.Sprintf("status %d is not documented", rec.Code)}
+	}
+	content, _ := resp["content"].(map[string]any)
+	if len(content) == 0 {
+		if rec.Body.Len() > 0 {
+			return []string{fmt.Sprintf("status %d is documented without a body, got %q", rec.Code, rec.Body.String())}
+		}
+		return nil
+	}
+	mediaType, _, err := mime.ParseMediaType(rec.Header().Get("Content-Type"))
+	if err != nil {
+		return []string{fmt.Sprintf("bad Content-Type %q", rec.Header().Get("Content-Type"))}
+	}
+	media := d.get(content, mediaType)
+	if media == nil {
+		return []string{fmt.Sprintf("Content-Type %s is not documented for status %d", mediaType, rec.Code)}
+	}
+	schema := d.get(media, "schema")
+	if schema == nil || mediaType != "application/json" {
+		return nil
+	}
+	var body any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		return []string{fmt.Sprintf("body is not JSON: %v", err)}
+	}
+	return d.check("body", schema, body)
+}
+
+// check returns the ways v does not match schema. Properties missing from a
+// schema that lists properties are reported too, so undocumented fields are
+// caught.
+func (d schemaDoc) check(at string, schema map[string]any, v any) []string {
+	schema = d.resolve(schema)
+	if all, ok := schema["allOf"].([]any); ok {
+		merged := map[string]any{"type": "object", "properties": map[string]any{}}
+		for _, s := range all {
+			s := d.resolve(s.(map[string]any))
+			for name, p := range d.propertiesOf(s) {
+				merged["properties"].(map[string]any)[name] = p
+			}
+			if req, ok := s["required"].([]any); ok {
+				merged["required"] = append(toSlice(merged["required"]), req...)
+			}
+		}
+		return d.check(at, merged, v)
+	}
+	if v == nil {
+		if schema["nullable"] == true {
+			return nil
+		}
+		return []string{at + " is null"}
+	}
+	switch schema["type"] {
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return []string{fmt.Sprintf("%s is %T, want an object", at, v)}
+		}
+		var problems []string
+		for _, name := range toSlice(schema["required"]) {
+			if _, ok := obj[name.(string)]; !ok {
+				problems = append(problems, fmt.Sprintf("%s.%s is missing", at, name))
+			}
+		}
+		props := d.propertiesOf(schema)
+		extra, _ := schema["additionalProperties"].(map[string]any)
+		for name, value := range obj {
+			if p, ok := props[name]; ok {
+				problems = append(problems, d.check(at+"."+name, p, value)...)
+			} else if extra != nil {
+				problems = append(problems, d.check(at+"."+name, extra, value)...)
+			} else if len(props) > 0 && schema["additionalProperties"] != true {
+				problems = append(problems, fmt.Sprintf("%s.%s is not documented", at, name))
+			}
+		}
+		return problems
+	case "array":
+		items, ok := v.([]any)
+		if !ok {
+			return []string{fmt.Sprintf("%s is %T, want an array", at, v)}
+		}
+		itemSchema, _ := schema["items"].(map[string]any)
+		var problems []string
+		for i, item := range items {
+			if itemSchema != nil {
+				problems = append(problems, d.check(fmt.Sprintf("%s[%d]", at, i), itemSchema, item)...)
+			}
+		}
+		return problems
+	case "string":
+		s, ok := v.(string)
+		if !ok {
+			return []string{fmt.Sprintf("%s is %T, want a string", at, v)}
+		}
+		if enum := toSlice(schema["enum"]); len(enum) > 0 {
+			for _, e := range enum {
+				if e == s {
+					return nil
+				}
+			}
+			return []string{fmt.Sprintf("%s is %q, not one of %v", at, s, enum)}
+		}
+		switch schema["format"] {
+		case "date-time":
+			if _, err := time.Parse(time.RFC3339, s); err != nil {
+				return []string{fmt.Sprintf("%s is %q, want an RFC 3339 timestamp", at, s)}
+			}
+		case "date":
+			if _, err := time.Parse("2006-01-02", s); err != nil {
+				return []string{fmt.Sprintf("%s is %q, want a date", at, s)}
+			}
+		}
+	case "integer", "number":
+		n, ok := v.(float64)
+		if !ok {
+			return []string{fmt.Sprintf("%s is %T, want a number", at, v)}
+		}
+		if schema["type"] == "integer" && n != float64(int64(n)) {
+			return []string{fmt.Sprintf("%s is %v, want an integer", at, n)}
+		}
+		if min, ok := schema["minimum"].(float64); ok && n < min {
+			return []string{fmt.Sprintf("%s is %v, below the minimum %v", at, n, min)}
+		}
+		if max, ok := schema["maximum"].(float64); ok && n > max {
+			return []string{fmt.Sprintf("%s is %v, above the maximum %v", at, n, max)}
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return []string{fmt.Sprintf("%s is %T, want a boolean", at, v)}
+		}
+	}
+	return nil
+}
+
+func (d schemaDoc) propertiesOf(schema map[string]any) map[string]map[string]any {
+	out := make(map[string]map[string]any)
+	props, _ := schema["properties"].(map[string]any)
+	for name, p := range props {
+		out[name] = p.(map[string]any)
+	}
+	return out
+}
+
+func toSlice(v any) []any {
+	s, _ := v.([]any)
+	return s
+}
@@ -1,8 +1,11 @@
 package http
 
 import (
+	"log/slog"
 	"net/http"
+	"time"
 
+	"tibia-nemesis-api/internal/logging"
 	"tibia-nemesis-api/internal/service"
 
 	"github.com/go-chi/chi/v5"
@@ -12,15 +15,87 @@ import (
 func NewRouter(svc *service.Service) http.Handler {
 	r := chi.NewRouter()
 	r.Use(middleware.RequestID)
-	r.Use(middleware.Logger)
+	r.Use(instrument(r))
+	r.Use(logRequests)
 	r.Use(middleware.Recoverer)
+	r.NotFound(notFoundHandler)
+	r.MethodNotAllowed(methodNotAllowedHandler)
 
 	h := NewHandlers(svc)
-	r.Get("/api/v1/status", h.Status)
-	r.Get("/api/v1/worlds", h.Worlds)
-	r.Get("/api/v1/bosses", h.Bosses)
-	r.Get("/api/v1/boss/{name}/history", h.BossHistory)
-	r.Post("/api/v1/refresh", h.Refresh)
+	// Requests are validated against openapi.json; admin requests only once
+	// they are authenticated, so a bad body doesn't hide a missing token
+	routes := r
+	r.Group(func(r chi.Router) {
+		r.Use(validateRequests(routes))
+		r.Get("/metrics", h.Metrics)
+		r.Get("/api/v1/status", h.Status)
+		r.Get("/api/v1/openapi.json", h.OpenAPI)
+		r.Get("/api/v1/worlds", h.Worlds)
+		r.Get("/api/v1/bosses", h.Bosses)
+		r.Get("/api/v1/boss/{name}", h.Boss)
+		r.Get("/api/v1/boss/{name}/history", h.BossHistory)
+		r.Get("/api/v1/boss/{name}/kills", h.BossKills)
+		r.Get("/api/v1/boss/{name}/forecast", h.Forecast)
+		r.Get("/api/v1/analysis/inclusion-ranges", h.InclusionRangeAnalysis)
+		r.Get("/api/v1/calendar.ics", h.Calendar)
+		r.Get("/api/v1/export", h.Export)
+		r.Get("/api/v1/metadata/bosses", h.BossMetadata)
+		r.Get("/api/v1/metadata/world-groups", h.WorldGroups)
+		r.Post("/api/v1/refresh", h.Refresh)
+	})
+
+	r.Group(func(r chi.Router) {
+		r.Use(h.requireAdmin)
+		r.Use(validateRequests(routes))
+		r.Post("/api/v1/admin/metadata/reload", h.ReloadMetadata)
+		r.Post("/api/v1/admin/metadata/bosses", h.CreateBossMetadata)
+		r.Put("/api/v1/admin/metadata/bosses/{name}", h.UpdateBossMetadata)
+		r.Patch("/api/v1/admin/metadata/bosses/{name}", h.PatchBossMetadata)
+		r.Delete("/api/v1/admin/metadata/bosses/{name}", h.DeleteBossMetadata)
+		r.Get("/api/v1/admin/metadata/audit", h.MetadataAudit)
+		r.Get("/api/v1/admin/metadata/export", h.ExportMetadata)
+		r.Get("/api/v1/admin/backups", h.ListBackups)
+		r.Post("/api/v1/admin/backups", h.CreateBackup)
+		r.Post("/api/v1/admin/maintenance", h.Maintain)
+
+		// Webhooks make the server POST to any URL, and watches and alerts
+		// hold subscribers' data, so they are admin only too
+		r.Get("/api/v1/webhooks", h.ListWebhooks)
+		r.Post("/api/v1/webhooks", h.CreateWebhook)
+		r.Get("/api/v1/webhooks/{id}", h.GetWebhook)
+		r.Put("/api/v1/webhooks/{id}", h.UpdateWebhook)
+		r.Delete("/api/v1/webhooks/{id}", h.DeleteWebhook)
+		r.Get("/api/v1/webhooks/{id}/deliveries", h.WebhookDeliveries)
+		r.Post("/api/v1/webhooks/{id}/test", h.TestWebhook)
+
+		r.Get("/api/v1/subscribers/{subscriber}/watches", h.ListWatches)
+		r.Post("/api/v1/subscribers/{subscriber}/watches", h.CreateWatch)
+		r.Delete("/api/v1/subscribers/{subscriber}/watches/{id}", h.DeleteWatch)
+		r.Get("/api/v1/alerts", h.Alerts)
+	})
+
+	// openapi.json is maintained by hand; flag routes that were added without documenting them
+	for _, route := range MissingFromSpec(r) {
+		slog.Warn("route is missing from openapi.json", "route", route)
+	}
 
 	return r
 }
+
+// logRequests tags the request's log lines with its ID and logs the request
+// once it is served
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		r = r.WithContext(logging.WithRequestID(r.Context(), middleware.GetReqID(r.Context())))
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		slog.InfoContext(r.Context(), "request", "method", r.Method, "path", r.URL.RequestURI(), "status", status,
+			"bytes", ww.BytesWritten(), "duration_ms", time.Since(start).Milliseconds(), "remote", r.RemoteAddr)
+	})
+}
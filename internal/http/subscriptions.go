@@ -0,0 +1,76 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"tibia-nemesis-api/internal/models"
+	"tibia-nemesis-api/internal/store"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func (h *Handlers) ListWatches(w http.ResponseWriter, r *http.Request) {
+	list, err := h.svc.Watches(r.Context(), chi.URLParam(r, "subscriber"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (h *Handlers) CreateWatch(w http.ResponseWriter, r *http.Request) {
+	var watch models.Watch
+	if err := json.NewDecoder(r.Body).Decode(&watch); err != nil {
+		writeError(w, r, badReq("invalid JSON body"))
+		return
+	}
+	watch.Subscriber = chi.URLParam(r, "subscriber")
+	if err := h.svc.CreateWatch(r.Context(), &watch); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, watch)
+}
+
+func (h *Handlers) DeleteWatch(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		writeError(w, r, badReq("invalid watch id"))
+		return
+	}
+	if err := h.svc.DeleteWatch(r.Context(), chi.URLParam(r, "subscriber"), id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handlers) Alerts(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f := store.AlertFilter{
+		Subscriber: q.Get("subscriber"),
+		World:      q.Get("world"),
+	}
+	if s := q.Get("since"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			writeError(w, r, badReq("since must be an RFC 3339 timestamp"))
+			return
+		}
+		f.Since = t
+	}
+	if s := q.Get("limit"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v > 0 {
+			f.Limit = v
+		}
+	}
+	list, err := h.svc.Alerts(r.Context(), f)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
@@ -0,0 +1,146 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"tibia-nemesis-api/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type webhookRequest struct {
+	URL              string   `json:"url"`
+	Secret           string   `json:"secret"`
+	Events           []string `json:"events"`
+	World            string   `json:"world"`
+	Bosses           []string `json:"bosses"`
+	ThresholdPercent *int     `json:"threshold_percent"`
+	Active           *bool    `json:"active"`
+}
+
+func (req webhookRequest) apply(wh *models.Webhook) {
+	wh.URL = req.URL
+	wh.Events = req.Events
+	wh.World = req.World
+	wh.Bosses = req.Bosses
+	wh.ThresholdPercent = req.ThresholdPercent
+	if req.Active != nil {
+		wh.Active = *req.Active
+	}
+}
+
+func (h *Handlers) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	list, err := h.svc.Webhooks(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (h *Handlers) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req webhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, badReq("invalid JSON body"))
+		return
+	}
+	wh := models.Webhook{Secret: req.Secret, Active: true}
+	req.apply(&wh)
+	if err := h.svc.CreateWebhook(r.Context(), &wh); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	// The secret is only ever returned here
+	writeJSON(w, http.StatusCreated, wh)
+}
+
+func (h *Handlers) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+	wh, err := h.svc.Webhook(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, wh)
+}
+
+func (h *Handlers) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+	wh, err := h.svc.Webhook(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	var req webhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, badReq("invalid JSON body"))
+		return
+	}
+	req.apply(wh)
+	if err := h.svc.UpdateWebhook(r.Context(), wh); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, wh)
+}
+
+func (h *Handlers) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+	if err := h.svc.DeleteWebhook(r.Context(), id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handlers) WebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+	limit := 25
+	if s := r.URL.Query().Get("limit"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v > 0 {
+			limit = v
+		}
+	}
+	list, err := h.svc.WebhookDeliveries(r.Context(), id, limit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (h *Handlers) TestWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+	d, err := h.svc.TestWebhook(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, d)
+}
+
+func webhookID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		writeError(w, r, badReq("invalid webhook id"))
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,88 @@
+// Package logging sets up the structured logger and carries attributes such as
+// request and refresh run IDs in contexts, so every line logged with that
+// context can be correlated.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// New returns a logger writing lines at level and above as "text" (key=value)
+// or "json". Records logged with a context get the attributes added to it
+// with With.
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("unknown log level %q", level)
+	}
+	opts := &slog.HandlerOptions{Level: l}
+	var h slog.Handler
+	switch strings.ToLower(format) {
+	case "text":
+		h = slog.NewTextHandler(w, opts)
+	case "json":
+		h = slog.NewJSONHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+	return slog.New(contextHandler{h}), nil
+}
+
+type attrsKey struct{}
+
+// With returns a copy of ctx whose log records get the attributes in args,
+// given as in slog.Logger.With
+func With(ctx context.Context, args ...any) context.Context {
+	attrs := append(attrsFrom(ctx), slog.Group("", args...).Value.Group()...)
+	return context.WithValue(ctx, attrsKey{}, attrs)
+}
+
+// WithRequestID tags the log records of a request with its ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return With(ctx, "request_id", id)
+}
+
+// WithRunID tags the log records of a refresh run with a new run ID, unless
+// ctx belongs to a run already
+func WithRunID(ctx context.Context) context.Context {
+	for _, a := range attrsFrom(ctx) {
+		if a.Key == "run_id" {
+			return ctx
+		}
+	}
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return With(ctx, "run_id", hex.EncodeToString(b))
+}
+
+func attrsFrom(ctx context.Context) []slog.Attr {
+	attrs, _ := ctx.Value(attrsKey{}).([]slog.Attr)
+	// Copied so contexts derived from the same parent don't share an array
+	return append([]slog.Attr(nil), attrs...)
+}
+
+// contextHandler adds the attributes of the record's context
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if attrs, ok := ctx.Value(attrsKey{}).([]slog.Attr); ok {
+		r.AddAttrs(attrs...)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,236 @@
+// Package metrics keeps counters, gauges and histograms and writes them in the
+// Prometheus text exposition format. Metrics are declared where they are
+// recorded and registered with Default when created.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Buckets for latencies in seconds
+var (
+	DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+	QueryBuckets   = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1}
+)
+
+// Default is the registry the constructors register with
+var Default = &Registry{}
+
+// Registry is a set of metrics written out together
+type Registry struct {
+	mu      sync.Mutex
+	metrics []*vec
+}
+
+func (r *Registry) register(v *vec) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, m := range r.metrics {
+		if m.name == v.name {
+			panic("metrics: " + v.name + " registered twice")
+		}
+	}
+	r.metrics = append(r.metrics, v)
+	sort.Slice(r.metrics, func(i, j int) bool { return r.metrics[i].name < r.metrics[j].name })
+}
+
+// WriteTo writes every metric in the text exposition format
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	metrics := append([]*vec(nil), r.metrics...)
+	r.mu.Unlock()
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	for _, m := range metrics {
+		m.write(bw)
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+// ContentType is the media type of the text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// vec is a metric with one series per combination of label values
+type vec struct {
+	name    string
+	help    string
+	kind    string // counter, gauge or histogram
+	labels  []string
+	buckets []float64 // Upper bounds, histograms only
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	labels []string
+	value  float64  // Counter and gauge value, histogram sum
+	counts []uint64 // Per bucket, not cumulative; histograms only
+	count  uint64
+}
+
+func newVec(name, help, kind string, labels []string, buckets []float64) *vec {
+	v := &vec{name: name, help: help, kind: kind, labels: labels, buckets: buckets, series: map[string]*series{}}
+	Default.register(v)
+	return v
+}
+
+// get returns the series for the label values, creating it on first use.
+// v.mu must be held.
+func (v *vec) get(values []string) *series {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", v.name, len(v.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	s, ok := v.series[key]
+	if !ok {
+		s = &series{labels: append([]string(nil), values...)}
+		if v.buckets != nil {
+			s.counts = make([]uint64, len(v.buckets))
+		}
+		v.series[key] = s
+	}
+	return s
+}
+
+func (v *vec) write(w *bufio.Writer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if len(v.series) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", v.name, helpEscaper.Replace(v.help), v.name, v.kind)
+	keys := make([]string, 0, len(v.series))
+	for k := range v.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		s := v.series[k]
+		if v.kind != "histogram" {
+			writeSample(w, v.name, v.labels, s.labels, "", s.value)
+			continue
+		}
+		var cumulative uint64
+		for i, upper := range v.buckets {
+			cumulative += s.counts[i]
+			writeSample(w, v.name+"_bucket", v.labels, s.labels, formatFloat(upper), float64(cumulative))
+		}
+		writeSample(w, v.name+"_bucket", v.labels, s.labels, "+Inf", float64(s.count))
+		writeSample(w, v.name+"_sum", v.labels, s.labels, "", s.value)
+		writeSample(w, v.name+"_count", v.labels, s.labels, "", float64(s.count))
+	}
+}
+
+// writeSample writes one line; le is added as the last label when set
+func writeSample(w *bufio.Writer, name string, labels, values []string, le string, value float64) {
+	w.WriteString(name)
+	if len(labels) > 0 || le != "" {
+		w.WriteByte('{')
+		for i, l := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, `%s="%s"`, l, labelEscaper.Replace(values[i]))
+		}
+		if le != "" {
+			if len(labels) > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, `le="%s"`, le)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Counter is a value that only goes up, per combination of label values
+type Counter struct{ v *vec }
+
+// NewCounter registers a counter with the given label names
+func NewCounter(name, help string, labels ...string) *Counter {
+	return &Counter{newVec(name, help, "counter", labels, nil)}
+}
+
+// Inc adds one to the series of the label values
+func (c *Counter) Inc(values ...string) { c.Add(1, values...) }
+
+// Add adds n, which must not be negative, to the series of the label values
+func (c *Counter) Add(n float64, values ...string) {
+	c.v.mu.Lock()
+	c.v.get(values).value += n
+	c.v.mu.Unlock()
+}
+
+// Gauge is a value that can go up and down, per combination of label values
+type Gauge struct{ v *vec }
+
+// NewGauge registers a gauge with the given label names
+func NewGauge(name, help string, labels ...string) *Gauge {
+	return &Gauge{newVec(name, help, "gauge", labels, nil)}
+}
+
+// Set sets the series of the label values
+func (g *Gauge) Set(value float64, values ...string) {
+	g.v.mu.Lock()
+	g.v.get(values).value = value
+	g.v.mu.Unlock()
+}
+
+// Histogram counts observations into buckets, per combination of label values
+type Histogram struct{ v *vec }
+
+// NewHistogram registers a histogram with the given bucket upper bounds,
+// which must be sorted, and label names
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	return &Histogram{newVec(name, help, "histogram", labels, buckets)}
+}
+
+// Observe records value in the series of the label values
+func (h *Histogram) Observe(value float64, values ...string) {
+	i := sort.SearchFloat64s(h.v.buckets, value) // First bucket with upper bound >= value
+	h.v.mu.Lock()
+	s := h.v.get(values)
+	if i < len(s.counts) {
+		s.counts[i]++
+	}
+	s.count++
+	s.value += value
+	h.v.mu.Unlock()
+}
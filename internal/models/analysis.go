@@ -0,0 +1,83 @@
+package models
+
+import "time"
+
+// IntervalStats summarizes the days between consecutive kills of a boss
+type IntervalStats struct {
+	Min int     `json:"min"`
+	P05 float64 `json:"p05"`
+	P25 float64 `json:"p25"`
+	P50 float64 `json:"p50"`
+	P75 float64 `json:"p75"`
+	P95 float64 `json:"p95"`
+	Max int     `json:"max"`
+}
+
+// InclusionRangeSuggestion compares the observed kill intervals of a boss with its configured inclusion_range
+type InclusionRangeSuggestion struct {
+	ID         string          `json:"id"`
+	Name       string          `json:"name"`
+	Kills      int             `json:"kills"`
+	Samples    int             `json:"samples"` // Kill intervals, across all worlds
+	Worlds     int             `json:"worlds"`
+	Intervals  *IntervalStats  `json:"intervals,omitempty"`
+	Suggested  *InclusionRange `json:"suggested,omitempty"` // Only with enough samples
+	Configured *InclusionRange `json:"configured,omitempty"`
+
+	// Kills observed more than a day sooner than the configured min_days, while the boss was hidden
+	BelowMin     int    `json:"below_min"`
+	Contradicted bool   `json:"contradicted"`
+	Reason       string `json:"reason,omitempty"`
+}
+
+// InclusionRangeAnalysis is the result of the kill interval analysis
+type InclusionRangeAnalysis struct {
+	GeneratedAt time.Time                  `json:"generated_at"`
+	MinSamples  int                        `json:"min_samples"`
+	Bosses      []InclusionRangeSuggestion `json:"bosses"`
+}
+
+// BacktestResult scores the inclusion range of one boss against its history.
+// A day is positive when the boss was killed within the horizon after it.
+type BacktestResult struct {
+	Name          string  `json:"name"`
+	Days          int     `json:"days"` // Observed days with a complete horizon
+	Positive      int     `json:"positive"`
+	Hits          int     `json:"hits"` // Positive days marked spawnable
+	Recall        float64 `json:"recall"`
+	SpawnableDays int     `json:"spawnable_days"`
+	NoiseDays     int     `json:"noise_days"` // Spawnable days without a kill in the horizon
+	NoiseRate     float64 `json:"noise_rate"`
+}
+
+// BacktestReport is the result of replaying stored history through the inclusion ranges
+type BacktestReport struct {
+	Horizon int              `json:"horizon_days"`
+	World   string           `json:"world,omitempty"`
+	Bosses  []BacktestResult `json:"bosses"`
+	Total   BacktestResult   `json:"total"`
+}
+
+// ForecastDay is the chance that a boss is killed on one day, and by the end of it
+type ForecastDay struct {
+	Date          string  `json:"date"` // YYYY-MM-DD
+	DaysSinceKill int     `json:"days_since_kill"`
+	Percent       float64 `json:"percent"`
+	Cumulative    float64 `json:"cumulative_percent"`
+}
+
+// Forecast describes when a boss is expected to spawn. Earliest, likely and
+// latest are the dates the cumulative chance reaches 5%, 50% and 95%; they are
+// null when that is more than a year away.
+type Forecast struct {
+	ID            string        `json:"id"`
+	Name          string        `json:"name"`
+	World         string        `json:"world"`
+	DaysSinceKill int           `json:"days_since_kill"` // As of today
+	ObservedAt    time.Time     `json:"observed_at"`
+	Confidence    string        `json:"confidence"`
+	Earliest      *string       `json:"earliest"`
+	Likely        *string       `json:"likely"`
+	Latest        *string       `json:"latest"`
+	Days          []ForecastDay `json:"days"`
+}
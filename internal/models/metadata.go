@@ -1,16 +1,65 @@
 package models
 
+import "time"
+
 type InclusionRange struct {
 	MinDays int `yaml:"min_days" json:"min_days"`
 	MaxDays int `yaml:"max_days" json:"max_days"`
 }
 
+// Coordinates are in-game map coordinates
+type Coordinates struct {
+	X int `yaml:"x" json:"x"`
+	Y int `yaml:"y" json:"y"`
+	Z int `yaml:"z" json:"z"`
+}
+
+type Location struct {
+	Description string       `yaml:"description,omitempty" json:"description,omitempty"`
+	Coordinates *Coordinates `yaml:"coordinates,omitempty" json:"coordinates,omitempty"`
+}
+
 type BossMetadata struct {
+	ID             string          `yaml:"id,omitempty" json:"id"` // Stable slug, derived from Name when omitted
 	Name           string          `yaml:"name" json:"name"`
+	Aliases        []string        `yaml:"aliases,omitempty" json:"aliases,omitempty"`
+	Category       string          `yaml:"category,omitempty" json:"category,omitempty"` // Region, e.g. "Zao", "Yalahar"
+	Location       *Location       `yaml:"location,omitempty" json:"location,omitempty"`
+	MultiSpawn     bool            `yaml:"multi_spawn,omitempty" json:"multi_spawn"` // Spawns at one of several places, like the Zunzu variants
+	EventOnly      bool            `yaml:"event_only,omitempty" json:"event_only"`
+	WikiURL        string          `yaml:"wiki_url,omitempty" json:"wiki_url,omitempty"`
+	Note           string          `yaml:"note,omitempty" json:"note,omitempty"`
 	InclusionRange *InclusionRange `yaml:"inclusion_range,omitempty" json:"inclusion_range,omitempty"`
+	Hidden         bool            `yaml:"hidden,omitempty" json:"hidden,omitempty"` // Left out of boss lists entirely
+
+	// Per-world changes; see MetadataOverride
+	Overrides []MetadataOverride `yaml:"overrides,omitempty" json:"overrides,omitempty"`
+}
+
+// MetadataOverride replaces a boss's inclusion range or hidden flag on some
+// worlds. It applies to the listed Worlds or to every world of Group; an
+// override for the world itself wins over a group override, which wins over
+// the boss's global values.
+type MetadataOverride struct {
+	Worlds         []string        `yaml:"worlds,omitempty" json:"worlds,omitempty"`
+	Group          string          `yaml:"group,omitempty" json:"group,omitempty"`
+	InclusionRange *InclusionRange `yaml:"inclusion_range,omitempty" json:"inclusion_range,omitempty"`
+	Hidden         *bool           `yaml:"hidden,omitempty" json:"hidden,omitempty"`
 }
 
 type BossMetadataFile struct {
-	Comment string                  `yaml:"_comment" json:"_comment"`
-	Bosses  map[string]BossMetadata `yaml:"bosses" json:"bosses"`
+	Comment     string                  `yaml:"_comment" json:"_comment"`
+	WorldGroups map[string][]string     `yaml:"world_groups,omitempty" json:"world_groups,omitempty"` // Group name -> worlds
+	Bosses      map[string]BossMetadata `yaml:"bosses" json:"bosses"`
+}
+
+// MetadataAuditEntry records one change to the stored boss metadata
+type MetadataAuditEntry struct {
+	ID     int64         `json:"id"`
+	Actor  string        `json:"actor"`
+	Action string        `json:"action"` // create, update, delete
+	Boss   string        `json:"boss"`
+	Before *BossMetadata `json:"before,omitempty"`
+	After  *BossMetadata `json:"after,omitempty"`
+	At     time.Time     `json:"at"`
 }
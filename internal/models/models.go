@@ -13,10 +13,23 @@ type SpawnChance struct {
 
 // BossInfo represents a boss in the API response with spawnable status
 type BossInfo struct {
+	ID            string `json:"id"`
 	Name          string `json:"name"`
 	Percent       *int   `json:"percent"`
 	DaysSinceKill *int   `json:"days_since_kill"`
 	Spawnable     bool   `json:"spawnable"`
+
+	// Our own estimate from the kill history and inclusion range; see service.estimateSpawn
+	EstimatedPercent *int   `json:"estimated_percent"`
+	Confidence       string `json:"confidence,omitempty"` // low, medium or high
+
+	// Joined from boss metadata
+	Category   string    `json:"category,omitempty"`
+	Location   *Location `json:"location,omitempty"`
+	MultiSpawn bool      `json:"multi_spawn"`
+	EventOnly  bool      `json:"event_only"`
+	WikiURL    string    `json:"wiki_url,omitempty"`
+	Note       string    `json:"note,omitempty"`
 }
 
 // BossesResponse is the wrapper for /api/v1/bosses endpoint
@@ -25,3 +38,11 @@ type BossesResponse struct {
 	UpdatedAt time.Time  `json:"updated_at"`
 	Bosses    []BossInfo `json:"bosses"`
 }
+
+// Kill is a boss kill inferred from the days_since_kill of a scrape
+type Kill struct {
+	World      string    `json:"world"`
+	Name       string    `json:"name"`
+	KilledOn   string    `json:"killed_on"` // YYYY-MM-DD, UTC
+	ObservedAt time.Time `json:"observed_at"`
+}
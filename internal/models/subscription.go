@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Watch conditions
+const (
+	ConditionSpawnable  = "spawnable"   // Boss becomes spawnable
+	ConditionPercentGTE = "percent_gte" // Percent >= Value
+	ConditionDaysGTE    = "days_gte"    // Days since kill >= Value
+)
+
+// Watch is a subscriber's interest in one boss on one world
+type Watch struct {
+	ID         int64     `json:"id"`
+	Subscriber string    `json:"subscriber"` // Opaque external ID, e.g. a Discord user ID
+	World      string    `json:"world"`
+	Boss       string    `json:"boss"`
+	Condition  string    `json:"condition"`
+	Value      *int      `json:"value,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	Held       bool      `json:"-"` // Whether the condition held at the last evaluation
+}
+
+// Alert is a triggered watch. Each watch fires at most once per Cycle.
+type Alert struct {
+	ID            int64     `json:"id"`
+	WatchID       int64     `json:"watch_id"`
+	Subscriber    string    `json:"subscriber"`
+	World         string    `json:"world"`
+	Boss          string    `json:"boss"`
+	Condition     string    `json:"condition"`
+	Value         *int      `json:"value,omitempty"`
+	Percent       *int      `json:"percent"`
+	DaysSinceKill *int      `json:"days_since_kill"`
+	Cycle         string    `json:"cycle"`
+	TriggeredAt   time.Time `json:"triggered_at"`
+}
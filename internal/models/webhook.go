@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+// Webhook events
+const (
+	EventRefreshCompleted = "refresh.completed"
+	EventBossThreshold    = "boss.threshold"
+	EventWatchTriggered   = "watch.triggered"
+	EventPing             = "ping"
+)
+
+// Delivery states
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+	DeliveryDropped   = "dropped" // Not sent: the webhook was deactivated or deleted first
+)
+
+// Webhook is a registered HTTP callback
+type Webhook struct {
+	ID               int64     `json:"id"`
+	URL              string    `json:"url"`
+	Secret           string    `json:"secret,omitempty"` // Only returned on create
+	Events           []string  `json:"events"`
+	World            string    `json:"world,omitempty"`  // Empty matches all worlds
+	Bosses           []string  `json:"bosses,omitempty"` // Empty matches all bosses (boss.threshold only)
+	ThresholdPercent *int      `json:"threshold_percent,omitempty"`
+	Active           bool      `json:"active"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// WebhookDelivery is a queued payload for a webhook
+type WebhookDelivery struct {
+	ID            int64      `json:"id"`
+	WebhookID     int64      `json:"webhook_id"`
+	Event         string     `json:"event"`
+	Payload       string     `json:"payload"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+}
+
+// DeliveryAttempt is one entry in the delivery log
+type DeliveryAttempt struct {
+	DeliveryID  int64     `json:"delivery_id"`
+	Attempt     int       `json:"attempt"`
+	StatusCode  int       `json:"status_code,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	DurationMs  int64     `json:"duration_ms"`
+	AttemptedAt time.Time `json:"attempted_at"`
+}
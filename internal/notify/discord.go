@@ -0,0 +1,331 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"tibia-nemesis-api/internal/metrics"
+	"tibia-nemesis-api/internal/models"
+)
+
+// Discord limits, see https://discord.com/developers/docs/resources/message#embed-object-embed-limits
+const (
+	maxEmbedsPerMessage = 10
+	maxCharsPerMessage  = 6000
+	maxFieldsPerEmbed   = 25
+	maxTitleLen         = 256
+	maxFieldNameLen     = 256
+	maxFieldValueLen    = 1024
+	maxFooterLen        = 2048
+
+	colorHighChance = 0xE74C3C
+	colorOverdue    = 0xE67E22
+	colorSpawnable  = 0x2ECC71
+
+	maxPostAttempts = 3
+	queueSize       = 1024 // Digests waiting to be posted, a few per world and run
+)
+
+var discordPosts = metrics.NewCounter("tibia_nemesis_discord_posts_total",
+	"Discord digest messages by outcome: delivered, failed (after retries) or dropped (queue full or closed)", "outcome")
+
+type EmbedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+type EmbedFooter struct {
+	Text string `json:"text"`
+}
+
+type Embed struct {
+	Title     string       `json:"title,omitempty"`
+	Color     int          `json:"color,omitempty"`
+	Fields    []EmbedField `json:"fields,omitempty"`
+	Footer    *EmbedFooter `json:"footer,omitempty"`
+	Timestamp string       `json:"timestamp,omitempty"`
+}
+
+// Message is a Discord webhook execute payload
+type Message struct {
+	Username string  `json:"username,omitempty"`
+	Content  string  `json:"content,omitempty"`
+	Embeds   []Embed `json:"embeds"`
+}
+
+// Discord renders world digests and posts them to Discord webhook URLs
+type Discord struct {
+	HighChance int
+	client     *http.Client
+	queue      chan digest
+	done       chan struct{}
+
+	mu     sync.RWMutex // Guards closed, so no digest is sent on a closed queue
+	closed bool
+}
+
+// digest is a world's queued digest for one webhook URL
+type digest struct {
+	ctx      context.Context // Carries the run ID for log lines
+	world    string
+	url      string
+	messages []Message
+}
+
+func NewDiscord(highChance int) *Discord {
+	return &Discord{
+		HighChance: highChance,
+		client:     &http.Client{Timeout: 15 * time.Second},
+		queue:      make(chan digest, queueSize),
+		done:       make(chan struct{}),
+	}
+}
+
+// Enqueue queues a world's digest for url and returns at once, so rate limits
+// and retries don't hold up the caller. Run posts queued digests in order.
+// It reports false when the queue is full or closed and the digest was dropped.
+func (d *Discord) Enqueue(ctx context.Context, world, url string, messages []Message) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		discordPosts.Add(float64(len(messages)), "dropped")
+		slog.ErrorContext(ctx, "discord: closed, digest dropped", "world", world, "messages", len(messages))
+		return false
+	}
+	select {
+	case d.queue <- digest{ctx: ctx, world: world, url: url, messages: messages}:
+		return true
+	default:
+		discordPosts.Add(float64(len(messages)), "dropped")
+		slog.ErrorContext(ctx, "discord: queue full, digest dropped", "world", world, "messages", len(messages))
+		return false
+	}
+}
+
+// Run posts queued digests until Close is called
+func (d *Discord) Run() {
+	defer close(d.done)
+	for q := range d.queue {
+		if err := d.Post(q.url, q.messages); err != nil {
+			slog.ErrorContext(q.ctx, "discord: digest failed", "world", q.world, "err", err)
+			continue
+		}
+		slog.InfoContext(q.ctx, "discord: posted digest", "world", q.world, "messages", len(q.messages))
+	}
+}
+
+// Close stops accepting digests and waits for Run to post the queued ones.
+// Calling it again only waits.
+func (d *Discord) Close() {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.queue)
+	}
+	d.mu.Unlock()
+	<-d.done
+}
+
+// RenderDigest groups the spawnable bosses of a world into high-chance, overdue and
+// spawnable embeds and splits them across as many messages as Discord's limits require.
+// Overdue means days since kill reached the boss's inclusion_range max_days.
+func (d *Discord) RenderDigest(resp *models.BossesResponse, metadata map[string]models.BossMetadata) []Message {
+	var high, overdue, spawnable []models.BossInfo
+	for _, b := range resp.Bosses {
+		if !b.Spawnable {
+			continue
+		}
+		switch {
+		case b.Percent != nil && *b.Percent >= d.HighChance:
+			high = append(high, b)
+		case isOverdue(b, metadata):
+			overdue = append(overdue, b)
+		default:
+			spawnable = append(spawnable, b)
+		}
+	}
+	sortBosses(high)
+	sortBosses(overdue)
+	sortBosses(spawnable)
+
+	footer := &EmbedFooter{Text: truncate(fmt.Sprintf("%s · data from tibia-statistic.com", resp.World), maxFooterLen)}
+	ts := resp.UpdatedAt.UTC().Format(time.RFC3339)
+	reserved := len([]rune(footer.Text))
+
+	var embeds []Embed
+	embeds = append(embeds, groupEmbeds(fmt.Sprintf("%s - High chance (≥%d%%)", resp.World, d.HighChance), colorHighChance, high, reserved)...)
+	embeds = append(embeds, groupEmbeds(fmt.Sprintf("%s - Overdue", resp.World), colorOverdue, overdue, reserved)...)
+	embeds = append(embeds, groupEmbeds(fmt.Sprintf("%s - Spawnable", resp.World), colorSpawnable, spawnable, reserved)...)
+	if len(embeds) == 0 {
+		embeds = append(embeds, Embed{Title: truncate(fmt.Sprintf("%s - No spawnable bosses", resp.World), maxTitleLen)})
+	}
+	for i := range embeds {
+		embeds[i].Footer = footer
+		embeds[i].Timestamp = ts
+	}
+
+	var messages []Message
+	cur := Message{Username: "Tibia Nemesis"}
+	size := 0
+	for _, e := range embeds {
+		n := embedLen(e)
+		if len(cur.Embeds) == maxEmbedsPerMessage || (len(cur.Embeds) > 0 && size+n > maxCharsPerMessage) {
+			messages = append(messages, cur)
+			cur = Message{Username: "Tibia Nemesis"}
+			size = 0
+		}
+		cur.Embeds = append(cur.Embeds, e)
+		size += n
+	}
+	return append(messages, cur)
+}
+
+// Post delivers messages in order, retrying on rate limits and server errors
+func (d *Discord) Post(url string, messages []Message) error {
+	for i, m := range messages {
+		body, err := json.Marshal(m)
+		if err != nil {
+			return err
+		}
+		if err := d.post(url, body); err != nil {
+			discordPosts.Inc("failed")
+			return fmt.Errorf("message %d/%d: %w", i+1, len(messages), err)
+		}
+		discordPosts.Inc("delivered")
+	}
+	return nil
+}
+
+func (d *Discord) post(url string, body []byte) error {
+	var lastErr error
+	for attempt := 1; attempt <= maxPostAttempts; attempt++ {
+		resp, err := d.client.Post(url, "application/json", bytes.NewReader(body))
+		if err != nil {
+			lastErr = err
+			time.Sleep(time.Duration(attempt) * time.Second)
+			continue
+		}
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		resp.Body.Close()
+
+		switch {
+		case resp.StatusCode >= 200 && resp.StatusCode <= 299:
+			return nil
+		case resp.StatusCode == http.StatusTooManyRequests:
+			wait := time.Second
+			if v, err := strconv.ParseFloat(resp.Header.Get("Retry-After"), 64); err == nil {
+				wait = time.Duration(v * float64(time.Second))
+			}
+			slog.Warn("discord: rate limited", "retry_in", wait.String())
+			lastErr = fmt.Errorf("HTTP %d", resp.StatusCode)
+			time.Sleep(wait)
+		case resp.StatusCode >= 500:
+			lastErr = fmt.Errorf("HTTP %d", resp.StatusCode)
+			time.Sleep(time.Duration(attempt) * time.Second)
+		default:
+			return fmt.Errorf("HTTP %d", resp.StatusCode)
+		}
+	}
+	return lastErr
+}
+
+// groupEmbeds lists bosses in as many embeds as the field count and total
+// length limits require. reserved is the length of the footer added later.
+func groupEmbeds(title string, color int, bosses []models.BossInfo, reserved int) []Embed {
+	var out []Embed
+	size := 0
+	for _, b := range bosses {
+		f := EmbedField{
+			Name:   truncate(b.Name, maxFieldNameLen),
+			Value:  truncate(bossLine(b), maxFieldValueLen),
+			Inline: true,
+		}
+		n := len([]rune(f.Name)) + len([]rune(f.Value))
+		if len(out) == 0 || len(out[len(out)-1].Fields) == maxFieldsPerEmbed || size+n > maxCharsPerMessage {
+			t := title
+			if len(out) > 0 {
+				t += " (cont.)"
+			}
+			out = append(out, Embed{Title: truncate(t, maxTitleLen), Color: color})
+			size = embedLen(out[len(out)-1]) + reserved
+		}
+		out[len(out)-1].Fields = append(out[len(out)-1].Fields, f)
+		size += n
+	}
+	return out
+}
+
+func bossLine(b models.BossInfo) string {
+	chance := "no prediction"
+	if b.Percent != nil {
+		chance = fmt.Sprintf("%d%%", *b.Percent)
+	}
+	if b.DaysSinceKill == nil {
+		return chance
+	}
+	return fmt.Sprintf("%s · %d days", chance, *b.DaysSinceKill)
+}
+
+func isOverdue(b models.BossInfo, metadata map[string]models.BossMetadata) bool {
+	meta, ok := metadata[b.Name]
+	if !ok || meta.InclusionRange == nil || b.DaysSinceKill == nil {
+		return false
+	}
+	return *b.DaysSinceKill >= meta.InclusionRange.MaxDays
+}
+
+// sortBosses orders by percent (highest first), then days since kill, then name
+func sortBosses(list []models.BossInfo) {
+	sort.SliceStable(list, func(i, j int) bool {
+		pi, pj := -1, -1
+		if list[i].Percent != nil {
+			pi = *list[i].Percent
+		}
+		if list[j].Percent != nil {
+			pj = *list[j].Percent
+		}
+		if pi != pj {
+			return pi > pj
+		}
+		di, dj := -1, -1
+		if list[i].DaysSinceKill != nil {
+			di = *list[i].DaysSinceKill
+		}
+		if list[j].DaysSinceKill != nil {
+			dj = *list[j].DaysSinceKill
+		}
+		if di != dj {
+			return di > dj
+		}
+		return list[i].Name < list[j].Name
+	})
+}
+
+func embedLen(e Embed) int {
+	n := len([]rune(e.Title))
+	for _, f := range e.Fields {
+		n += len([]rune(f.Name)) + len([]rune(f.Value))
+	}
+	if e.Footer != nil {
+		n += len([]rune(e.Footer.Text))
+	}
+	return n
+}
+
+func truncate(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max-1]) + "…"
+}
@@ -0,0 +1,107 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"tibia-nemesis-api/internal/models"
+)
+
+func intp(v int) *int { return &v }
+
+func TestRenderDigestWithinLimits(t *testing.T) {
+	// 60 bosses with the longest field names: 25 of them alone exceed an
+	// embed's 6000 characters
+	resp := &models.BossesResponse{World: "Antica", UpdatedAt: time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)}
+	for i := 0; i < 60; i++ {
+		resp.Bosses = append(resp.Bosses, models.BossInfo{
+			Name:          fmt.Sprintf("%03d %s", i, strings.Repeat("x", 300)),
+			Percent:       intp(i % 100),
+			DaysSinceKill: intp(i),
+			Spawnable:     true,
+		})
+	}
+	d := NewDiscord(50)
+	messages := d.RenderDigest(resp, nil)
+
+	fields := 0
+	for i, m := range messages {
+		if len(m.Embeds) > maxEmbedsPerMessage {
+			t.Errorf("message %d has %d embeds", i, len(m.Embeds))
+		}
+		total := 0
+		for j, e := range m.Embeds {
+			n := embedLen(e)
+			if n > maxCharsPerMessage {
+				t.Errorf("message %d embed %d has %d characters", i, j, n)
+			}
+			if len(e.Fields) > maxFieldsPerEmbed {
+				t.Errorf("message %d embed %d has %d fields", i, j, len(e.Fields))
+			}
+			total += n
+			fields += len(e.Fields)
+		}
+		if total > maxCharsPerMessage {
+			t.Errorf("message %d has %d characters", i, total)
+		}
+	}
+	if fields != len(resp.Bosses) {
+		t.Errorf("digest lists %d bosses, want %d", fields, len(resp.Bosses))
+	}
+}
+
+func TestEnqueueDoesNotWaitForRateLimits(t *testing.T) {
+	var mu sync.Mutex
+	var posted []Message
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "0.5")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		var m Message
+		if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+			t.Error(err)
+		}
+		posted = append(posted, m)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	d := NewDiscord(50)
+	go d.Run()
+	messages := []Message{{Content: "first"}, {Content: "second"}}
+	start := time.Now()
+	if !d.Enqueue(context.Background(), "Antica", srv.URL, messages) {
+		t.Fatal("digest was dropped")
+	}
+	if waited := time.Since(start); waited > 100*time.Millisecond {
+		t.Errorf("Enqueue took %v", waited)
+	}
+	d.Close()
+
+	if len(posted) != 2 || posted[0].Content != "first" || posted[1].Content != "second" {
+		t.Errorf("posted %+v, want both messages in order", posted)
+	}
+}
+
+func TestEnqueueAfterClose(t *testing.T) {
+	d := NewDiscord(50)
+	go d.Run()
+	d.Close()
+	d.Close() // Closing twice only waits
+	if d.Enqueue(context.Background(), "Antica", "http://127.0.0.1:0", []Message{{Content: "late"}}) {
+		t.Error("digest queued after Close")
+	}
+}
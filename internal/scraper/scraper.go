@@ -1,8 +1,9 @@
 package scraper
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"regexp"
 	"strconv"
@@ -10,6 +11,7 @@ import (
 	"time"
 
 	"tibia-nemesis-api/internal/config"
+	"tibia-nemesis-api/internal/metrics"
 	"tibia-nemesis-api/internal/models"
 
 	"github.com/PuerkitoBio/goquery"
@@ -25,8 +27,23 @@ var (
 	whitespaceRE    = regexp.MustCompile(`\s+`)
 )
 
+var (
+	scrapes        = metrics.NewCounter("tibia_nemesis_scrapes_total", "Scrapes of the source site by world and result (success or error)", "world", "result")
+	scrapeDuration = metrics.NewHistogram("tibia_nemesis_scrape_duration_seconds", "Duration of scrapes, fetch and parse", metrics.DefaultBuckets, "world")
+	scrapedBosses  = metrics.NewGauge("tibia_nemesis_scrape_bosses", "Bosses parsed by the last successful scrape of a world", "world")
+)
+
+// StatusError is returned when the source site answers with a non-200 status
+type StatusError struct {
+	StatusCode int
+	URL        string
+	RetryAfter string
+}
+
+func (e *StatusError) Error() string { return fmt.Sprintf("HTTP %d", e.StatusCode) }
+
 type Scraper interface {
-	Fetch(world string) ([]models.SpawnChance, error)
+	Fetch(ctx context.Context, world string) ([]models.SpawnChance, error)
 }
 
 type WebScraper struct {
@@ -37,30 +54,39 @@ func New(cfg config.Config) Scraper {
 	return &WebScraper{cfg: cfg}
 }
 
-func (w *WebScraper) Fetch(world string) ([]models.SpawnChance, error) {
-	return w.fetchHTML(world)
+func (w *WebScraper) Fetch(ctx context.Context, world string) ([]models.SpawnChance, error) {
+	start := time.Now()
+	list, err := w.fetchHTML(ctx, world)
+	scrapeDuration.Observe(time.Since(start).Seconds(), world)
+	if err != nil {
+		scrapes.Inc(world, "error")
+		return nil, err
+	}
+	scrapes.Inc(world, "success")
+	scrapedBosses.Set(float64(len(list)), world)
+	return list, nil
 }
 
-func (w *WebScraper) fetchHTML(world string) ([]models.SpawnChance, error) {
-	url := fmt.Sprintf("https://www.tibia-statistic.com/bosshunter/details/%s", strings.ToLower(world))
+func (w *WebScraper) fetchHTML(ctx context.Context, world string) ([]models.SpawnChance, error) {
+	url := strings.Replace(w.cfg.SourceURL, "%s", strings.ToLower(world), 1)
 
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("User-Agent", "TibiaNemesisAPI/1.0")
 
-	client := &http.Client{Timeout: 15 * time.Second}
+	client := &http.Client{Timeout: w.cfg.SourceTimeout}
 	resp, err := client.Do(req)
 	if err != nil {
-		log.Printf("scraper: fetch failed for %s: %v", url, err)
+		slog.WarnContext(ctx, "scraper: fetch failed", "url", url, "err", err)
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		log.Printf("scraper: HTTP %d for %s", resp.StatusCode, url)
-		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+		slog.WarnContext(ctx, "scraper: unexpected status", "url", url, "status", resp.StatusCode)
+		return nil, &StatusError{StatusCode: resp.StatusCode, URL: url, RetryAfter: resp.Header.Get("Retry-After")}
 	}
 
 	doc, err := goquery.NewDocumentFromReader(resp.Body)
@@ -73,10 +99,10 @@ func (w *WebScraper) fetchHTML(world string) ([]models.SpawnChance, error) {
 		return nil, err
 	}
 
-	return w.parseSpawnChances(world, html)
+	return w.parseSpawnChances(ctx, world, html)
 }
 
-func (w *WebScraper) parseSpawnChances(world, html string) ([]models.SpawnChance, error) {
+func (w *WebScraper) parseSpawnChances(ctx context.Context, world, html string) ([]models.SpawnChance, error) {
 	var result []models.SpawnChance
 	now := time.Now().UTC()
 
@@ -123,22 +149,23 @@ func (w *WebScraper) parseSpawnChances(world, html string) ([]models.SpawnChance
 				IsNoChance:    isNoChance,
 				UpdatedAt:     now,
 			})
-
-			logMsg := fmt.Sprintf("scraper: %s - %s:", world, name)
-			if percent != nil {
-				logMsg += fmt.Sprintf(" %d%%", *percent)
-			}
-			if days != nil {
-				logMsg += fmt.Sprintf(" (%d days)", *days)
-			}
-			log.Print(logMsg)
+			slog.DebugContext(ctx, "scraper: parsed boss", "world", world, "boss", name,
+				"percent", deref(percent), "days_since_kill", deref(days), "no_chance", isNoChance)
 		}
 	}
 
-	log.Printf("scraper: parsed %d bosses for %s", len(result), world)
+	slog.InfoContext(ctx, "scraper: parsed bosses", "world", world, "bosses", len(result))
 	return result, nil
 }
 
+// deref returns the value of v for logging, or nil when it is unknown
+func deref(v *int) any {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
 func cleanHTMLText(text string) string {
 	text = htmlTagRE.ReplaceAllString(text, "")
 	text = whitespaceRE.ReplaceAllString(text, " ")
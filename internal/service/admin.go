@@ -0,0 +1,175 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"strings"
+
+	"tibia-nemesis-api/internal/models"
+	"tibia-nemesis-api/internal/store"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultMetadataComment heads exports when no metadata file was ever imported
+const defaultMetadataComment = "Boss metadata for API inclusion_range filtering.\n"
+
+// Authenticate returns the name of the admin owning token
+func (s *Service) Authenticate(token string) (string, error) {
+	if len(s.cfg.AdminTokens) == 0 {
+		return "", &Error{Code: CodeUnauthorized, Message: "admin API is disabled, set ADMIN_TOKENS to enable it"}
+	}
+	for _, t := range s.cfg.AdminTokens {
+		if subtle.ConstantTimeCompare([]byte(t.Token), []byte(token)) == 1 {
+			return t.Name, nil
+		}
+	}
+	return "", &Error{Code: CodeUnauthorized, Message: "missing or invalid admin token"}
+}
+
+// BossMetadataEntry returns the metadata of one boss, looked up by name, ID or alias
+func (s *Service) BossMetadataEntry(ctx context.Context, key string) (*models.BossMetadata, error) {
+	m := s.metadata()
+	name, ok := m.resolver.Resolve(key)
+	if !ok {
+		return nil, unknownBoss(key)
+	}
+	meta := m.bosses[name]
+	return &meta, nil
+}
+
+// CreateBossMetadata adds metadata for a new boss
+func (s *Service) CreateBossMetadata(ctx context.Context, actor string, meta models.BossMetadata) (*models.BossMetadata, error) {
+	s.metaMu.Lock()
+	defer s.metaMu.Unlock()
+	meta.Name = strings.TrimSpace(meta.Name)
+	if _, ok := s.metadata().bosses[meta.Name]; ok {
+		return nil, &Error{
+			Code:    CodeConflict,
+			Message: fmt.Sprintf("boss %q already has metadata", meta.Name),
+			Details: map[string]any{"boss": meta.Name},
+		}
+	}
+	if err := s.changeMetadata(actor, store.MetadataChange{After: &meta}); err != nil {
+		return nil, err
+	}
+	return &meta, nil
+}
+
+// UpdateBossMetadata replaces the metadata of the boss known as key. Changing
+// the name renames the boss.
+func (s *Service) UpdateBossMetadata(ctx context.Context, actor, key string, meta models.BossMetadata) (*models.BossMetadata, error) {
+	s.metaMu.Lock()
+	defer s.metaMu.Unlock()
+	before, err := s.BossMetadataEntry(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	meta.Name = strings.TrimSpace(meta.Name)
+	if meta.Name != before.Name {
+		if _, ok := s.metadata().bosses[meta.Name]; ok {
+			return nil, &Error{
+				Code:    CodeConflict,
+				Message: fmt.Sprintf("boss %q already has metadata", meta.Name),
+				Details: map[string]any{"boss": meta.Name},
+			}
+		}
+	}
+	if err := s.changeMetadata(actor, store.MetadataChange{Before: before, After: &meta}); err != nil {
+		return nil, err
+	}
+	return &meta, nil
+}
+
+// DeleteBossMetadata removes the metadata of the boss known as key
+func (s *Service) DeleteBossMetadata(ctx context.Context, actor, key string) error {
+	s.metaMu.Lock()
+	defer s.metaMu.Unlock()
+	before, err := s.BossMetadataEntry(ctx, key)
+	if err != nil {
+		return err
+	}
+	return s.changeMetadata(actor, store.MetadataChange{Before: before})
+}
+
+// changeMetadata validates the metadata set as it would be after c, then
+// stores c with its audit entry and activates the result. Callers hold metaMu.
+func (s *Service) changeMetadata(actor string, c store.MetadataChange) error {
+	cur := s.metadata()
+	next := make(map[string]models.BossMetadata, len(cur.bosses)+1)
+	for name, meta := range cur.bosses {
+		next[name] = meta
+	}
+	if c.Before != nil {
+		delete(next, c.Before.Name)
+	}
+	if c.After != nil {
+		if c.After.ID == "" {
+			c.After.ID = Slug(c.After.Name)
+		}
+		next[c.After.Name] = *c.After
+	}
+	if problems := ValidateBossMetadata(next, cur.groups); len(problems) > 0 {
+		return &Error{
+			Code:    CodeValidation,
+			Message: "invalid boss metadata",
+			Details: map[string]any{"problems": []string(problems)},
+		}
+	}
+	if err := s.store.ApplyMetadataChanges(actor, []store.MetadataChange{c}); err != nil {
+		return err
+	}
+	return s.activateStoredMetadata(cur.path, cur.modTime)
+}
+
+// MetadataAudit returns the newest metadata changes first, optionally for one boss
+func (s *Service) MetadataAudit(ctx context.Context, boss string, limit int) ([]models.MetadataAuditEntry, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	// Deleted bosses no longer resolve, so their exact name is used as given
+	if name, ok := s.resolveBoss(boss); ok {
+		boss = name
+	}
+	list, err := s.store.ListMetadataAudit(boss, limit)
+	if err != nil {
+		return nil, err
+	}
+	if list == nil {
+		list = []models.MetadataAuditEntry{}
+	}
+	return list, nil
+}
+
+// ExportBossMetadata renders the active metadata in the bosses_metadata.yaml format
+func (s *Service) ExportBossMetadata(ctx context.Context) ([]byte, error) {
+	comment, err := s.store.GetSetting(settingMetadataComment)
+	if err != nil {
+		return nil, err
+	}
+	if comment == "" {
+		comment = defaultMetadataComment
+	}
+	m := s.metadata()
+	file := models.BossMetadataFile{Comment: comment, WorldGroups: m.groups, Bosses: make(map[string]models.BossMetadata)}
+	for name, meta := range m.bosses {
+		// Derived IDs are left out, as in the hand-written file
+		if meta.ID == Slug(meta.Name) {
+			meta.ID = ""
+		}
+		file.Bosses[name] = meta
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(file); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
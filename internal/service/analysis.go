@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"time"
+
+	"tibia-nemesis-api/internal/models"
+)
+
+// killDateLayout is the format of models.Kill.KilledOn
+const killDateLayout = "2006-01-02"
+
+// minAnalysisSamples is the number of kill intervals needed before a range is suggested
+const minAnalysisSamples = 5
+
+// Kills returns the newest inferred kills of a boss on world first
+func (s *Service) Kills(ctx context.Context, world, name string, limit int) ([]models.Kill, error) {
+	world, err := s.canonicalWorld(world)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = 25
+	}
+	canonical, known := s.resolveBoss(name)
+	list, err := s.store.GetKills(world, canonical, limit)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		if !known {
+			return nil, unknownBoss(name)
+		}
+		list = []models.Kill{}
+	}
+	return list, nil
+}
+
+// InclusionRangeAnalysis returns the cached kill interval analysis, computing
+// it on first use. It is recomputed after every scheduled refresh.
+func (s *Service) InclusionRangeAnalysis(ctx context.Context, contradictedOnly bool) (*models.InclusionRangeAnalysis, error) {
+	a := s.analysis.Load()
+	if a == nil {
+		var err error
+		if a, err = s.refreshAnalysis(); err != nil {
+			return nil, err
+		}
+	}
+	if !contradictedOnly {
+		return a, nil
+	}
+	filtered := *a
+	filtered.Bosses = []models.InclusionRangeSuggestion{}
+	for _, b := range a.Bosses {
+		if b.Contradicted {
+			filtered.Bosses = append(filtered.Bosses, b)
+		}
+	}
+	return &filtered, nil
+}
+
+// bossKills summarizes the kill history of one boss across all worlds
+type bossKills struct {
+	kills     int
+	intervals []int // Days between consecutive kills on the same world, sorted
+	worlds    map[string]bool
+}
+
+// killHistory groups every stored kill by canonical boss name
+func (s *Service) killHistory() (map[string]*bossKills, int, error) {
+	kills, err := s.store.AllKills()
+	if err != nil {
+		return nil, 0, err
+	}
+	byBoss := make(map[string]*bossKills)
+	last := make(map[[2]string]time.Time) // boss, world -> previous kill
+	for _, k := range kills {
+		day, err := time.Parse(killDateLayout, k.KilledOn)
+		if err != nil {
+			continue
+		}
+		name, _ := s.resolveBoss(k.Name)
+		b := byBoss[name]
+		if b == nil {
+			b = &bossKills{worlds: make(map[string]bool)}
+			byBoss[name] = b
+		}
+		b.kills++
+		b.worlds[k.World] = true
+		key := [2]string{name, k.World}
+		if prev, ok := last[key]; ok {
+			b.intervals = append(b.intervals, int(day.Sub(prev).Hours()/24))
+		}
+		last[key] = day
+	}
+	for _, b := range byBoss {
+		sort.Ints(b.intervals)
+	}
+	return byBoss, len(kills), nil
+}
+
+// refreshAnalysis derives kill intervals per boss across all worlds and
+// compares them with the configured inclusion ranges. The intervals are kept
+// for the spawn estimates.
+func (s *Service) refreshAnalysis() (*models.InclusionRangeAnalysis, error) {
+	history, kills, err := s.killHistory()
+	if err != nil {
+		return nil, err
+	}
+	s.kills.Store(&history)
+	meta := s.metadata().bosses
+
+	byBoss := make(map[string]*bossKills, len(history))
+	for name, b := range history {
+		byBoss[name] = b
+	}
+	// Configured bosses without kills are listed too, so missing data is visible
+	for name, m := range meta {
+		if m.InclusionRange != nil && byBoss[name] == nil {
+			byBoss[name] = &bossKills{worlds: map[string]bool{}}
+		}
+	}
+
+	out := &models.InclusionRangeAnalysis{
+		GeneratedAt: time.Now().UTC(),
+		MinSamples:  minAnalysisSamples,
+		Bosses:      make([]models.InclusionRangeSuggestion, 0, len(byBoss)),
+	}
+	for name, b := range byBoss {
+		sug := models.InclusionRangeSuggestion{
+			ID:         s.bossID(name),
+			Name:       name,
+			Kills:      b.kills,
+			Samples:    len(b.intervals),
+			Worlds:     len(b.worlds),
+			Configured: meta[name].InclusionRange,
+		}
+		if len(b.intervals) > 0 {
+			sug.Intervals = intervalStats(b.intervals)
+		}
+		if sug.Samples >= minAnalysisSamples {
+			sug.Suggested = &models.InclusionRange{
+				MinDays: int(math.Floor(sug.Intervals.P05)),
+				MaxDays: int(math.Ceil(sug.Intervals.P95)),
+			}
+		}
+		if r := sug.Configured; r != nil && sug.Intervals != nil {
+			sug.BelowMin = belowMin(b.intervals, r.MinDays)
+			if sug.BelowMin > 0 {
+				sug.Contradicted = true
+				sug.Reason = fmt.Sprintf("%d of %d kill intervals were more than a day shorter than min_days %d", sug.BelowMin, sug.Samples, r.MinDays)
+			}
+		}
+		out.Bosses = append(out.Bosses, sug)
+	}
+	sort.Slice(out.Bosses, func(i, j int) bool { return out.Bosses[i].Name < out.Bosses[j].Name })
+
+	s.analysis.Store(out)
+	slog.Info("analysis: computed", "kills", kills, "bosses", len(out.Bosses))
+	return out, nil
+}
+
+// belowMin counts the kill intervals that contradict min_days. Kill dates are
+// only known to a day, so an interval a day short of min_days is not counted.
+// Intervals longer than max_days are expected: a boss can be killed any time
+// after it spawned.
+func belowMin(intervals []int, minDays int) int {
+	n := 0
+	for _, d := range intervals {
+		if d < minDays-1 {
+			n++
+		}
+	}
+	return n
+}
+
+// intervalStats summarizes sorted kill intervals
+func intervalStats(sorted []int) *models.IntervalStats {
+	return &models.IntervalStats{
+		Min: sorted[0],
+		P05: quantile(sorted, 0.05),
+		P25: quantile(sorted, 0.25),
+		P50: quantile(sorted, 0.50),
+		P75: quantile(sorted, 0.75),
+		P95: quantile(sorted, 0.95),
+		Max: sorted[len(sorted)-1],
+	}
+}
+
+// quantile interpolates linearly between the closest ranks, rounded to one decimal
+func quantile(sorted []int, q float64) float64 {
+	pos := q * float64(len(sorted)-1)
+	lo := int(math.Floor(pos))
+	hi := int(math.Ceil(pos))
+	v := float64(sorted[lo]) + (pos-float64(lo))*float64(sorted[hi]-sorted[lo])
+	return math.Round(v*10) / 10
+}
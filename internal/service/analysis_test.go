@@ -0,0 +1,22 @@
+package service
+
+import "testing"
+
+func TestBelowMin(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		intervals []int
+		minDays   int
+		want      int
+	}{
+		{"all within the range", []int{12, 20, 46}, 12, 0},
+		{"a day short is within the kill date's precision", []int{11, 14, 30}, 12, 0},
+		{"two days short", []int{10, 11, 30}, 12, 1},
+		{"longer than max_days", []int{60, 90, 120}, 12, 0},
+		{"no min_days", []int{1, 2}, 0, 0},
+	} {
+		if got := belowMin(tc.intervals, tc.minDays); got != tc.want {
+			t.Errorf("%s: belowMin(%v, %d) = %d, want %d", tc.name, tc.intervals, tc.minDays, got, tc.want)
+		}
+	}
+}
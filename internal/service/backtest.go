@@ -0,0 +1,180 @@
+package service
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"tibia-nemesis-api/internal/models"
+	"tibia-nemesis-api/internal/store"
+)
+
+// BacktestOptions selects what Backtest replays
+type BacktestOptions struct {
+	Horizon int    // Days after an observation in which a kill counts
+	World   string // Empty replays every world
+	Boss    string // Empty replays every boss
+}
+
+// Backtest replays the stored observations through ApplyInclusionRange with
+// the given metadata. For every observed day it checks whether the boss was
+// marked spawnable and whether it was actually killed within the horizon, so
+// a metadata change can be scored before it is deployed.
+func Backtest(st *store.SQLite, metadata *models.BossMetadataFile, opts BacktestOptions) (*models.BacktestReport, error) {
+	if opts.Horizon <= 0 {
+		opts.Horizon = 1
+	}
+	snap := &metadataSnapshot{
+		bosses:   metadata.Bosses,
+		groups:   metadata.WorldGroups,
+		resolver: NewBossResolver(metadata.Bosses),
+	}
+	boss := ""
+	if opts.Boss != "" {
+		var ok bool
+		if boss, ok = snap.resolver.Resolve(opts.Boss); !ok {
+			return nil, unknownBoss(opts.Boss)
+		}
+	}
+
+	kills, err := st.AllKills()
+	if err != nil {
+		return nil, err
+	}
+	killDays := make(map[[2]string][]time.Time) // world, boss -> kill days
+	for _, k := range kills {
+		day, err := time.Parse(killDateLayout, k.KilledOn)
+		if err != nil {
+			continue
+		}
+		key := [2]string{k.World, canonicalName(snap, k.Name)}
+		killDays[key] = append(killDays[key], day)
+	}
+
+	results := make(map[string]*models.BacktestResult)
+	worldMeta := make(map[string]map[string]models.BossMetadata)
+	var series []models.SpawnChance // Observations of the current world and boss, one per day
+	flush := func() {
+		if len(series) == 0 {
+			return
+		}
+		world, name := series[0].World, series[0].Name
+		meta := worldMeta[world]
+		if meta == nil {
+			meta = snap.forWorld(world)
+			worldMeta[world] = meta
+		}
+		if !meta[name].Hidden {
+			r := results[name]
+			if r == nil {
+				r = &models.BacktestResult{Name: name}
+				results[name] = r
+			}
+			scoreSeries(r, series, killDays[[2]string{world, name}], meta, opts.Horizon)
+		}
+		series = series[:0]
+	}
+
+	err = st.EachObservation(opts.World, time.Time{}, time.Time{}, func(o models.SpawnChance) error {
+		o.Name = canonicalName(snap, o.Name)
+		if boss != "" && o.Name != boss {
+			return nil
+		}
+		o.UpdatedAt = day(o.UpdatedAt)
+		if n := len(series); n > 0 {
+			last := series[n-1]
+			if last.World != o.World || last.Name != o.Name {
+				flush()
+			} else if last.UpdatedAt.Equal(o.UpdatedAt) {
+				series[n-1] = o // Keep the day's last scrape
+				return nil
+			}
+		}
+		series = append(series, o)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	flush()
+
+	report := &models.BacktestReport{Horizon: opts.Horizon, World: opts.World, Bosses: []models.BacktestResult{}}
+	for _, r := range results {
+		finishResult(r)
+		report.Bosses = append(report.Bosses, *r)
+		report.Total.Days += r.Days
+		report.Total.Positive += r.Positive
+		report.Total.Hits += r.Hits
+		report.Total.SpawnableDays += r.SpawnableDays
+		report.Total.NoiseDays += r.NoiseDays
+	}
+	report.Total.Name = "total"
+	finishResult(&report.Total)
+	sort.Slice(report.Bosses, func(i, j int) bool { return report.Bosses[i].Name < report.Bosses[j].Name })
+	return report, nil
+}
+
+// scoreSeries scores one world's daily observations of a boss. Days whose
+// horizon reaches past the last observation are skipped: their outcome is unknown.
+func scoreSeries(r *models.BacktestResult, series []models.SpawnChance, kills []time.Time, meta map[string]models.BossMetadata, horizon int) {
+	end := series[len(series)-1].UpdatedAt
+	for _, o := range series {
+		if o.UpdatedAt.AddDate(0, 0, horizon).After(end) {
+			break
+		}
+		// The kill the observation already knows about does not count. Kill
+		// dates are only accurate to a day, see store.observationRecorder.
+		known := time.Time{}
+		if o.DaysSinceKill != nil {
+			known = o.UpdatedAt.AddDate(0, 0, 1-*o.DaysSinceKill)
+		}
+		positive := false
+		for _, k := range kills {
+			if k.After(known) && !k.Before(o.UpdatedAt) && k.Before(o.UpdatedAt.AddDate(0, 0, horizon)) {
+				positive = true
+				break
+			}
+		}
+		spawnable := len(ApplyInclusionRange([]models.SpawnChance{o}, meta)) > 0
+
+		r.Days++
+		if positive {
+			r.Positive++
+		}
+		if spawnable {
+			r.SpawnableDays++
+			if positive {
+				r.Hits++
+			} else {
+				r.NoiseDays++
+			}
+		}
+	}
+}
+
+func finishResult(r *models.BacktestResult) {
+	if r.Positive > 0 {
+		r.Recall = round2(float64(r.Hits) / float64(r.Positive))
+	}
+	if r.SpawnableDays > 0 {
+		r.NoiseRate = round2(float64(r.NoiseDays) / float64(r.SpawnableDays))
+	}
+}
+
+func round2(v float64) float64 {
+	return float64(int(v*100+0.5)) / 100
+}
+
+// canonicalName resolves name through the metadata, keeping unknown names as they are
+func canonicalName(snap *metadataSnapshot, name string) string {
+	if canonical, ok := snap.resolver.Resolve(name); ok {
+		return canonical
+	}
+	return strings.TrimSpace(name)
+}
+
+// day truncates t to its UTC date
+func day(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"tibia-nemesis-api/internal/models"
+)
+
+func TestScoreSeries(t *testing.T) {
+	meta := map[string]models.BossMetadata{
+		"Furyosa": {Name: "Furyosa", InclusionRange: &models.InclusionRange{MinDays: 3, MaxDays: 5}},
+	}
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	on := func(d int) time.Time { return start.AddDate(0, 0, d) }
+
+	// Killed on days 0, 5 and 7; each kill is first reported a day since kill
+	var series []models.SpawnChance
+	for d, days := range []int{1, 2, 3, 4, 5, 6, 1, 2, 1, 2} {
+		series = append(series, models.SpawnChance{
+			World: "Antica", Name: "Furyosa", Percent: intp(10), DaysSinceKill: intp(days), UpdatedAt: on(d),
+		})
+	}
+	kills := []time.Time{on(0), on(5), on(7)}
+
+	for _, tc := range []struct {
+		horizon int
+		want    models.BacktestResult
+	}{
+		// Days 0-8 are scored. The kill on day 0 is the one day 0 already
+		// knows about, so it is no positive. Spawnable on days 2-5, of which
+		// only day 5 is followed by a kill; the kill on day 7 is missed.
+		{1, models.BacktestResult{Days: 9, Positive: 2, Hits: 1, SpawnableDays: 4, NoiseDays: 3, Recall: 0.5, NoiseRate: 0.75}},
+		// Days 0-6 are scored: days 3-5 lead to the kill on day 5, day 6 to
+		// the one on day 7, and day 2 is spawnable too early
+		{3, models.BacktestResult{Days: 7, Positive: 4, Hits: 3, SpawnableDays: 4, NoiseDays: 1, Recall: 0.75, NoiseRate: 0.25}},
+	} {
+		r := models.BacktestResult{Name: "Furyosa"}
+		scoreSeries(&r, series, kills, meta, tc.horizon)
+		finishResult(&r)
+		tc.want.Name = "Furyosa"
+		if r != tc.want {
+			t.Errorf("horizon %d:\n got %+v\nwant %+v", tc.horizon, r, tc.want)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Snapshot files are named snapshot-<UTC time>.db, so they sort by age
+const (
+	snapshotPrefix = "snapshot-"
+	snapshotLayout = "20060102T150405Z"
+)
+
+// Backup describes a database snapshot in the backup directory
+type Backup struct {
+	Name      string    `json:"name"`
+	SizeBytes int64     `json:"size_bytes"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Backup snapshots the database into the backup directory and removes the
+// oldest snapshots beyond the configured number to keep
+func (s *Service) Backup(ctx context.Context) (*Backup, error) {
+	s.backupMu.Lock()
+	defer s.backupMu.Unlock()
+	dir := s.cfg.BackupDir
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	name := snapshotPrefix + now.Format(snapshotLayout) + ".db"
+	path := filepath.Join(dir, name)
+	if _, err := os.Stat(path); err == nil {
+		return nil, &Error{Code: CodeConflict, Message: fmt.Sprintf("snapshot %s already exists, retry in a second", name)}
+	}
+	if err := s.store.Backup(path); err != nil {
+		os.Remove(path)
+		return nil, fmt.Errorf("backup %s: %w", path, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	slog.InfoContext(ctx, "backup: wrote snapshot", "path", path, "bytes", info.Size())
+
+	backups, err := s.Backups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if keep := s.cfg.BackupKeep; keep > 0 && len(backups) > keep {
+		for _, b := range backups[keep:] {
+			if err := os.Remove(filepath.Join(dir, b.Name)); err != nil {
+				slog.ErrorContext(ctx, "backup: rotation failed", "err", err)
+				continue
+			}
+			slog.InfoContext(ctx, "backup: removed old snapshot", "name", b.Name)
+		}
+	}
+	return &Backup{Name: name, SizeBytes: info.Size(), CreatedAt: now.Truncate(time.Second)}, nil
+}
+
+// Backups lists the snapshots in the backup directory, newest first
+func (s *Service) Backups(ctx context.Context) ([]Backup, error) {
+	entries, err := os.ReadDir(s.cfg.BackupDir)
+	if os.IsNotExist(err) {
+		return []Backup{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	out := []Backup{}
+	for _, e := range entries {
+		stamp, ok := strings.CutPrefix(e.Name(), snapshotPrefix)
+		if !ok || e.IsDir() {
+			continue
+		}
+		created, err := time.Parse(snapshotLayout, strings.TrimSuffix(stamp, ".db"))
+		if err != nil {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		out = append(out, Backup{Name: e.Name(), SizeBytes: info.Size(), CreatedAt: created})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out, nil
+}
+
+// StartBackups snapshots the database at the configured interval
+func (s *Service) StartBackups() {
+	if s.cfg.BackupInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(s.cfg.BackupInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if _, err := s.Backup(context.Background()); err != nil {
+			slog.Error("backup: scheduled backup failed", "err", err)
+		}
+	}
+}
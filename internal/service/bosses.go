@@ -0,0 +1,80 @@
+package service
+
+import (
+	"strings"
+	"unicode"
+
+	"tibia-nemesis-api/internal/models"
+)
+
+// Slug turns a boss name into a stable identifier:
+// "Battlemaster Zunzu (West)" -> "battlemaster-zunzu-west", "Gaz'haragoth" -> "gazharagoth"
+func Slug(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r == '\'' || r == '’':
+			continue
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			dash = false
+			b.WriteRune(r)
+		default:
+			dash = true
+		}
+	}
+	return b.String()
+}
+
+// BossResolver maps names, IDs and aliases to the metadata name of a boss
+type BossResolver struct {
+	byKey map[string]string
+}
+
+func NewBossResolver(metadata map[string]models.BossMetadata) *BossResolver {
+	r := &BossResolver{byKey: make(map[string]string)}
+	for name, meta := range metadata {
+		r.add(name, name)
+		r.add(meta.ID, name)
+		for _, alias := range meta.Aliases {
+			r.add(alias, name)
+		}
+	}
+	return r
+}
+
+func (r *BossResolver) add(key, name string) {
+	if key == "" {
+		return
+	}
+	r.byKey[strings.ToLower(strings.TrimSpace(key))] = name
+	r.byKey[Slug(key)] = name
+}
+
+// Resolve returns the metadata name for a boss name, ID or alias (case-insensitive)
+func (r *BossResolver) Resolve(s string) (string, bool) {
+	if name, ok := r.byKey[strings.ToLower(strings.TrimSpace(s))]; ok {
+		return name, true
+	}
+	name, ok := r.byKey[Slug(s)]
+	return name, ok
+}
+
+// resolveBoss returns the canonical name for a boss, or s unchanged when it is unknown
+func (s *Service) resolveBoss(name string) (string, bool) {
+	if canonical, ok := s.metadata().resolver.Resolve(name); ok {
+		return canonical, true
+	}
+	return name, false
+}
+
+// bossID returns the metadata ID of a boss, or its slug when it has no metadata
+func (s *Service) bossID(name string) string {
+	if meta, ok := s.metadata().bosses[name]; ok && meta.ID != "" {
+		return meta.ID
+	}
+	return Slug(name)
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"tibia-nemesis-api/internal/models"
+)
+
+// worldCalendar holds the rendered events of one world, regenerated after
+// every refresh of the world and whenever the metadata changes
+type worldCalendar struct {
+	metadataVersion string
+	events          map[string]string // Boss name -> VEVENT
+}
+
+type calendarCache struct {
+	mu     sync.Mutex
+	worlds map[string]*worldCalendar
+}
+
+// Calendar renders an RFC 5545 feed with the spawn window of every boss of
+// world that has an inclusion range, or only of the given bosses
+func (s *Service) Calendar(ctx context.Context, world string, bosses []string) ([]byte, error) {
+	world, err := s.canonicalWorld(world)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	seen := make(map[string]bool)
+	for _, b := range bosses {
+		name, ok := s.resolveBoss(b)
+		if !ok {
+			return nil, unknownBoss(b)
+		}
+		if !seen[name] { // Aliases and repeats of a boss list its event once
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	s.calendars.mu.Lock()
+	cal := s.calendars.worlds[world]
+	s.calendars.mu.Unlock()
+	if cal == nil || cal.metadataVersion != s.metadata().version {
+		if cal, err = s.refreshCalendar(ctx, world); err != nil {
+			return nil, err
+		}
+	}
+
+	if names == nil {
+		for name := range cal.events {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	b.WriteString("BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//tibia-nemesis-api//boss windows//EN\r\nCALSCALE:GREGORIAN\r\n")
+	writeICSLine(&b, "X-WR-CALNAME:Boss windows "+world)
+	for _, name := range names {
+		b.WriteString(cal.events[name])
+	}
+	b.WriteString("END:VCALENDAR\r\n")
+	return []byte(b.String()), nil
+}
+
+// refreshCalendar renders the events of world and caches them
+func (s *Service) refreshCalendar(ctx context.Context, world string) (*worldCalendar, error) {
+	version := s.metadata().version
+	resp, err := s.Bosses(ctx, world)
+	if err != nil {
+		return nil, err
+	}
+	// Days since kill count from the boss's own observation
+	chances, err := s.store.GetSpawnChances(resp.World)
+	if err != nil {
+		return nil, err
+	}
+	observed := make(map[string]time.Time)
+	for _, c := range chances {
+		name, _ := s.resolveBoss(c.Name)
+		if c.UpdatedAt.After(observed[name]) {
+			observed[name] = c.UpdatedAt
+		}
+	}
+
+	ranges := s.metadata().forWorld(resp.World)
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+	cal := &worldCalendar{metadataVersion: version, events: make(map[string]string)}
+	for _, boss := range resp.Bosses {
+		meta := ranges[boss.Name]
+		if meta.InclusionRange == nil || boss.DaysSinceKill == nil {
+			continue
+		}
+		cal.events[boss.Name] = calendarEvent(resp.World, boss, meta, day(observed[boss.Name]), stamp)
+	}
+
+	s.calendars.mu.Lock()
+	if s.calendars.worlds == nil {
+		s.calendars.worlds = make(map[string]*worldCalendar)
+	}
+	s.calendars.worlds[resp.World] = cal
+	s.calendars.mu.Unlock()
+	return cal, nil
+}
+
+// calendarEvent renders the all-day spawn window of a boss as a VEVENT. The UID
+// only depends on the boss and world, so a new window replaces the old one.
+func calendarEvent(world string, boss models.BossInfo, meta models.BossMetadata, observed time.Time, stamp string) string {
+	r := meta.InclusionRange
+	killed := observed.AddDate(0, 0, -*boss.DaysSinceKill)
+	start := killed.AddDate(0, 0, r.MinDays)
+	end := killed.AddDate(0, 0, r.MaxDays+1) // DTEND is exclusive
+
+	desc := fmt.Sprintf("Last kill on %s: %s (%d days before %s).\nSpawn window: %d to %d days after a kill.",
+		world, killed.Format(killDateLayout), *boss.DaysSinceKill, observed.Format(killDateLayout), r.MinDays, r.MaxDays)
+	if boss.Percent != nil {
+		desc += fmt.Sprintf("\nChance when observed: %d%%.", *boss.Percent)
+	}
+
+	var b strings.Builder
+	b.WriteString("BEGIN:VEVENT\r\n")
+	writeICSLine(&b, fmt.Sprintf("UID:%s-%s@tibia-nemesis-api", boss.ID, strings.ToLower(world)))
+	writeICSLine(&b, "DTSTAMP:"+stamp)
+	writeICSLine(&b, "DTSTART;VALUE=DATE:"+start.Format("20060102"))
+	writeICSLine(&b, "DTEND;VALUE=DATE:"+end.Format("20060102"))
+	writeICSLine(&b, "SUMMARY:"+icsEscape(fmt.Sprintf("%s spawn window (%s)", boss.Name, world)))
+	writeICSLine(&b, "DESCRIPTION:"+icsEscape(desc))
+	if meta.Location != nil && meta.Location.Description != "" {
+		writeICSLine(&b, "LOCATION:"+icsEscape(meta.Location.Description))
+	}
+	if meta.WikiURL != "" {
+		writeICSLine(&b, "URL:"+meta.WikiURL)
+	}
+	writeICSLine(&b, "TRANSP:TRANSPARENT")
+	b.WriteString("END:VEVENT\r\n")
+	return b.String()
+}
+
+// icsEscape escapes a TEXT value (RFC 5545 section 3.3.11)
+func icsEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+// writeICSLine writes a content line, folded at 75 octets without splitting
+// UTF-8 sequences (RFC 5545 section 3.1)
+func writeICSLine(b *strings.Builder, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		limit = 74 // Continuation lines start with a space
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
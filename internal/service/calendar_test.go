@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"tibia-nemesis-api/internal/models"
+)
+
+func TestCalendarEvents(t *testing.T) {
+	svc, st, _ := newMetadataService(t, metadataV1)
+	ctx := context.Background()
+	observe := func(updated time.Time, days int) string {
+		t.Helper()
+		scrape := []models.SpawnChance{{Name: "Furyosa", DaysSinceKill: intp(days), UpdatedAt: updated}}
+		if err := st.UpsertSpawnChances("Antica", scrape); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := svc.refreshCalendar(ctx, "Antica"); err != nil {
+			t.Fatal(err)
+		}
+		cal, err := svc.Calendar(ctx, "antica", []string{"furyosa", "Furyosa", "FURYOSA"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(cal)
+	}
+	uid := regexp.MustCompile(`(?m)^UID:.*\r$`)
+
+	now := time.Now().UTC()
+	first := observe(now.AddDate(0, 0, -1), 3)
+	if n := strings.Count(first, "BEGIN:VEVENT"); n != 1 {
+		t.Errorf("%d events for one boss requested three times, want 1", n)
+	}
+	// A refresh after a new kill moves the window but keeps the UID, so
+	// calendar clients replace the event instead of adding one
+	second := observe(now, 1)
+	if first == second {
+		t.Fatal("the refresh did not change the event")
+	}
+	if a, b := uid.FindAllString(first, -1), uid.FindAllString(second, -1); len(a) != 1 || len(b) != 1 || a[0] != b[0] {
+		t.Errorf("UIDs %q before and %q after the refresh", a, b)
+	}
+}
+
+func TestICSLines(t *testing.T) {
+	if got, want := icsEscape("a\\b;c,d\ne"), `a\\b\;c\,d\ne`; got != want {
+		t.Errorf("icsEscape: %s, want %s", got, want)
+	}
+	for _, line := range []string{
+		"SUMMARY:short",
+		"DESCRIPTION:" + strings.Repeat("x", 63),                     // Exactly 75 octets
+		"DESCRIPTION:" + strings.Repeat("x", 62) + "ä" + "more text", // ä would straddle the fold
+		"DESCRIPTION:" + strings.Repeat("Dröhnung ", 40),
+	} {
+		var b strings.Builder
+		writeICSLine(&b, line)
+		out := b.String()
+		if !strings.HasSuffix(out, "\r\n") {
+			t.Errorf("%q is not terminated by CRLF", out)
+			continue
+		}
+		physical := strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n")
+		for i, p := range physical {
+			if len(p) > 75 {
+				t.Errorf("line %d of %q has %d octets", i, line, len(p))
+			}
+			if !utf8.ValidString(p) {
+				t.Errorf("line %d of %q splits a UTF-8 sequence: %q", i, line, p)
+			}
+			if i > 0 && !strings.HasPrefix(p, " ") {
+				t.Errorf("continuation line %d of %q does not start with a space", i, line)
+			}
+		}
+		if unfolded := strings.ReplaceAll(strings.TrimSuffix(out, "\r\n"), "\r\n ", ""); unfolded != line {
+			t.Errorf("unfolds to %q, want %q", unfolded, line)
+		}
+		if len(line) <= 75 && len(physical) != 1 {
+			t.Errorf("%q was folded", line)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+)
+
+// StartDigests posts queued Discord digests until FlushDigests is called
+func (s *Service) StartDigests() {
+	s.discord.Run()
+}
+
+// FlushDigests waits for the queued Discord digests to be posted. No digests
+// can be queued after it.
+func (s *Service) FlushDigests() {
+	s.discord.Close()
+}
+
+// postDigest queues the Discord digest for world to every configured Discord webhook
+// that wants it; StartDigests posts them without holding up the refresh
+func (s *Service) postDigest(ctx context.Context, world string) {
+	var urls []string
+	for _, hook := range s.cfg.DiscordWebhooks {
+		if hook.World == "" || strings.EqualFold(hook.World, world) {
+			urls = append(urls, hook.URL)
+		}
+	}
+	if len(urls) == 0 {
+		return
+	}
+
+	resp, err := s.Bosses(ctx, world)
+	if err != nil {
+		slog.ErrorContext(ctx, "discord: digest failed", "world", world, "err", err)
+		return
+	}
+	messages := s.discord.RenderDigest(resp, s.metadata().forWorld(resp.World))
+	for _, url := range urls {
+		s.discord.Enqueue(ctx, resp.World, url, messages)
+	}
+}
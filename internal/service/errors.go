@@ -0,0 +1,104 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"tibia-nemesis-api/internal/scraper"
+	"tibia-nemesis-api/internal/store"
+)
+
+// Code is a stable, machine readable error identifier returned to API clients
+type Code string
+
+const (
+	CodeValidation          Code = "validation_failed"
+	CodeNotFound            Code = "not_found"
+	CodeUnknownWorld        Code = "unknown_world"
+	CodeUnknownBoss         Code = "unknown_boss"
+	CodeConflict            Code = "conflict"
+	CodeUnauthorized        Code = "unauthorized"
+	CodeUpstreamUnavailable Code = "upstream_unavailable"
+	CodeRateLimited         Code = "rate_limited"
+	CodeInternal            Code = "internal_error"
+)
+
+// Error is a typed service error. Message is safe to show to clients; Err is
+// the underlying cause and is only logged.
+type Error struct {
+	Code    Code
+	Message string
+	Details map[string]any
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Message, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// AsError returns err as a *Error, treating anything untyped as an internal error
+func AsError(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return &Error{Code: CodeInternal, Message: "internal server error", Err: err}
+}
+
+func validationError(format string, args ...any) error {
+	return &Error{Code: CodeValidation, Message: fmt.Sprintf(format, args...)}
+}
+
+func unknownWorld(world string) error {
+	return &Error{
+		Code:    CodeUnknownWorld,
+		Message: fmt.Sprintf("unknown world %q", world),
+		Details: map[string]any{"world": world},
+	}
+}
+
+func unknownBoss(name string) error {
+	return &Error{
+		Code:    CodeUnknownBoss,
+		Message: fmt.Sprintf("unknown boss %q", name),
+		Details: map[string]any{"boss": name},
+	}
+}
+
+// notFound converts store.ErrNotFound into a typed not found error for what
+func notFound(err error, what string) error {
+	if errors.Is(err, store.ErrNotFound) {
+		return &Error{Code: CodeNotFound, Message: what + " not found", Err: err}
+	}
+	return err
+}
+
+// upstreamError classifies a scraper failure for world
+func upstreamError(world string, err error) error {
+	var se *scraper.StatusError
+	if errors.As(err, &se) {
+		switch se.StatusCode {
+		case http.StatusNotFound:
+			return &Error{Code: CodeUnknownWorld, Message: fmt.Sprintf("unknown world %q", world), Details: map[string]any{"world": world}, Err: err}
+		case http.StatusTooManyRequests:
+			details := map[string]any{"world": world}
+			if se.RetryAfter != "" {
+				details["retry_after"] = se.RetryAfter
+			}
+			return &Error{Code: CodeRateLimited, Message: "rate limited by the data source, try again later", Details: details, Err: err}
+		}
+		return &Error{Code: CodeUpstreamUnavailable, Message: "data source unavailable", Details: map[string]any{"world": world, "upstream_status": se.StatusCode}, Err: err}
+	}
+	var ne net.Error
+	if errors.As(err, &ne) {
+		return &Error{Code: CodeUpstreamUnavailable, Message: "data source unavailable", Details: map[string]any{"world": world}, Err: err}
+	}
+	return err
+}
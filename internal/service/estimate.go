@@ -0,0 +1,125 @@
+package service
+
+import (
+	"log/slog"
+	"math"
+
+	"tibia-nemesis-api/internal/models"
+)
+
+// priorWeight is how many kill intervals a metadata inclusion range counts as
+// when it is blended with the observed intervals
+const priorWeight = 5
+
+// Observed kill intervals needed for a medium and a high confidence estimate
+const (
+	mediumConfidenceSamples = 10
+	highConfidenceSamples   = 30
+)
+
+// Confidence levels of an estimate
+const (
+	ConfidenceLow    = "low"
+	ConfidenceMedium = "medium"
+	ConfidenceHigh   = "high"
+)
+
+// spawnEstimate is our own probability that a boss spawns today
+type spawnEstimate struct {
+	Percent    int
+	Confidence string
+}
+
+// estimateSpawn estimates the chance that a boss last killed days ago is
+// killed today, as a stand-in for its spawn chance. It is the discrete hazard
+// of the boss's kill interval distribution: observed intervals across all
+// worlds, blended with a uniform spawn over the inclusion range. Returns nil
+// when there is nothing to estimate from.
+func estimateSpawn(days *int, intervals []int, r *models.InclusionRange) *spawnEstimate {
+	if days == nil {
+		return nil
+	}
+	h, ok := dailyHazard(*days, intervals, r)
+	if !ok {
+		return nil
+	}
+	return &spawnEstimate{Percent: int(math.Round(100 * h)), Confidence: confidence(len(intervals))}
+}
+
+// dailyHazard is the chance of a kill on the day a boss reaches d days since
+// its last kill, given it was not killed before. ok is false without intervals or range.
+func dailyHazard(d int, intervals []int, r *models.InclusionRange) (h float64, ok bool) {
+	if len(intervals) == 0 && r == nil {
+		return 0, false
+	}
+	n := float64(len(intervals))
+	var weight float64
+	if len(intervals) > 0 {
+		h, weight = empiricalHazard(intervals, d)*n, n
+	}
+	if r != nil {
+		h += rangeHazard(*r, d) * priorWeight
+		weight += priorWeight
+	}
+	return h / weight, true
+}
+
+// confidence rates an estimate by the number of observed kill intervals behind it
+func confidence(samples int) string {
+	switch {
+	case samples >= highConfidenceSamples:
+		return ConfidenceHigh
+	case samples >= mediumConfidenceSamples:
+		return ConfidenceMedium
+	}
+	return ConfidenceLow
+}
+
+// empiricalHazard is P(interval = d | interval >= d) over sorted intervals.
+// Samples too small for a high confidence estimate are smoothed over
+// neighbouring days so they don't produce spikes. Once every observed interval
+// is shorter than d the boss is overdue.
+func empiricalHazard(sorted []int, d int) float64 {
+	var atRisk float64
+	var at [3]float64 // Counts for d-1, d and d+1
+	for _, v := range sorted {
+		if v >= d {
+			atRisk++
+		}
+		if i := v - d + 1; i >= 0 && i < 3 {
+			at[i]++
+		}
+	}
+	if atRisk == 0 {
+		return 1
+	}
+	if len(sorted) >= highConfidenceSamples {
+		return at[1] / atRisk
+	}
+	return math.Min(1, (at[0]+2*at[1]+at[2])/4/atRisk)
+}
+
+// rangeHazard assumes the boss spawns on one of the days of its inclusion
+// range with equal probability: none before min_days, certain from max_days
+func rangeHazard(r models.InclusionRange, d int) float64 {
+	if d < r.MinDays {
+		return 0
+	}
+	if d >= r.MaxDays {
+		return 1
+	}
+	return 1 / float64(r.MaxDays-d+1)
+}
+
+// killIntervals returns the observed kill intervals of every boss, loading
+// them on first use
+func (s *Service) killIntervals() map[string]*bossKills {
+	if k := s.kills.Load(); k != nil {
+		return *k
+	}
+	if _, err := s.refreshAnalysis(); err != nil {
+		slog.Error("analysis: kill history failed", "err", err)
+		return nil
+	}
+	return *s.kills.Load()
+}
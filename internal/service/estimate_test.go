@@ -0,0 +1,82 @@
+package service
+
+import (
+	"math"
+	"testing"
+
+	"tibia-nemesis-api/internal/models"
+)
+
+// repeat returns n intervals of each of the given lengths, sorted
+func repeat(n int, lengths ...int) []int {
+	var out []int
+	for _, l := range lengths {
+		for i := 0; i < n; i++ {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+func TestDailyHazard(t *testing.T) {
+	few := []int{10, 12, 14}       // Smoothed over neighbouring days
+	many := repeat(10, 10, 12, 14) // 30 samples, not smoothed
+	r := &models.InclusionRange{MinDays: 10, MaxDays: 20}
+	for _, tc := range []struct {
+		name      string
+		d         int
+		intervals []int
+		r         *models.InclusionRange
+		want      float64
+	}{
+		{"range before min_days", 5, nil, r, 0},
+		{"range from min_days", 10, nil, r, 1.0 / 11},
+		{"range the day before max_days", 19, nil, r, 1.0 / 2},
+		{"range at max_days", 20, nil, r, 1},
+		{"range past max_days", 25, nil, r, 1},
+		{"intervals, exact day", 12, many, nil, 10.0 / 20},
+		{"intervals, day without kills", 11, many, nil, 0},
+		{"intervals, smoothed", 12, few, nil, (0 + 2*1 + 0) / 4.0 / 2},
+		{"intervals, smoothed from both neighbours", 13, few, nil, (1 + 0 + 1) / 4.0 / 1},
+		{"intervals, smoothing capped at 1", 6, []int{5, 5, 5, 6}, nil, 1},
+		{"overdue: every interval is shorter", 15, few, nil, 1},
+		{"overdue with many samples", 15, many, nil, 1},
+		{"blended", 12, few, r, (0.25*3 + 1.0/9*priorWeight) / (3 + priorWeight)},
+		{"blended at max_days", 13, few, &models.InclusionRange{MinDays: 10, MaxDays: 13}, (0.5*3 + 1*priorWeight) / (3 + priorWeight)},
+		{"blended while overdue", 15, few, r, (1*3 + 1.0/6*priorWeight) / (3 + priorWeight)},
+	} {
+		h, ok := dailyHazard(tc.d, tc.intervals, tc.r)
+		if !ok {
+			t.Errorf("%s: no estimate", tc.name)
+			continue
+		}
+		if math.Abs(h-tc.want) > 1e-9 {
+			t.Errorf("%s: dailyHazard(%d) = %.4f, want %.4f", tc.name, tc.d, h, tc.want)
+		}
+	}
+	if _, ok := dailyHazard(12, nil, nil); ok {
+		t.Error("estimate without intervals or range")
+	}
+}
+
+func TestEstimateSpawn(t *testing.T) {
+	r := &models.InclusionRange{MinDays: 10, MaxDays: 20}
+	if e := estimateSpawn(nil, []int{10}, r); e != nil {
+		t.Errorf("estimate without days since kill: %+v", e)
+	}
+	for _, tc := range []struct {
+		intervals  []int
+		percent    int
+		confidence string
+	}{
+		{nil, 10, ConfidenceLow},                      // Range only: 1/10
+		{repeat(3, 10, 12, 14), 20, ConfidenceLow},    // (0.25·9 + 0.1·5) / 14
+		{repeat(4, 10, 12, 14), 21, ConfidenceMedium}, // (0.25·12 + 0.1·5) / 17
+		{repeat(10, 10, 12, 14), 1, ConfidenceHigh},   // No kill on day 11: 0.1·5 / 35
+	} {
+		e := estimateSpawn(intp(11), tc.intervals, r)
+		if e == nil || e.Percent != tc.percent || e.Confidence != tc.confidence {
+			t.Errorf("%d intervals: %+v, want %d%% %s", len(tc.intervals), e, tc.percent, tc.confidence)
+		}
+	}
+}
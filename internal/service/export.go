@@ -0,0 +1,29 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"tibia-nemesis-api/internal/models"
+)
+
+// Export calls fn for every stored observation of world (all worlds when
+// empty) observed in [from, to), ordered by world, boss and time. Zero bounds
+// are open. Observations are streamed from the store, never loaded at once.
+func (s *Service) Export(ctx context.Context, world string, from, to time.Time, fn func(models.SpawnChance) error) error {
+	if world != "" {
+		var err error
+		if world, err = s.canonicalWorld(world); err != nil {
+			return err
+		}
+	}
+	if !from.IsZero() && !to.IsZero() && !to.After(from) {
+		return validationError("from must be before to")
+	}
+	return s.store.EachObservation(world, from, to, func(o models.SpawnChance) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return fn(o)
+	})
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"tibia-nemesis-api/internal/models"
+)
+
+const (
+	defaultForecastDays = 14
+	maxForecastDays     = 90
+	// forecastReach bounds the search for the earliest, likely and latest dates
+	forecastReach = 365
+)
+
+// Forecast projects the daily kill chance of a boss over the next days,
+// starting today from its latest observation
+func (s *Service) Forecast(ctx context.Context, world, name string, days int) (*models.Forecast, error) {
+	if days == 0 {
+		days = defaultForecastDays
+	}
+	if days < 1 || days > maxForecastDays {
+		return nil, validationError("days must be between 1 and %d", maxForecastDays)
+	}
+	info, err := s.Boss(ctx, world, name)
+	if err != nil {
+		return nil, err
+	}
+	world, _ = s.canonicalWorld(world)
+	latest, err := s.store.GetBossHistory(world, info.Name, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(latest) == 0 || latest[0].DaysSinceKill == nil {
+		return nil, &Error{
+			Code:    CodeNotFound,
+			Message: fmt.Sprintf("no days since kill known for %s on %s", info.Name, world),
+			Details: map[string]any{"world": world, "boss": info.Name},
+		}
+	}
+	obs := latest[0]
+
+	var intervals []int
+	if h := s.killIntervals()[info.Name]; h != nil {
+		intervals = h.intervals
+	}
+	r := s.metadata().forWorld(world)[info.Name].InclusionRange
+	if _, ok := dailyHazard(*obs.DaysSinceKill, intervals, r); !ok {
+		return nil, &Error{
+			Code:    CodeNotFound,
+			Message: fmt.Sprintf("no kill history or inclusion range to forecast %s from", info.Name),
+			Details: map[string]any{"boss": info.Name},
+		}
+	}
+
+	// A stale observation is carried forward to today, assuming no kill since
+	start := day(obs.UpdatedAt)
+	if today := day(time.Now()); today.After(start) {
+		*obs.DaysSinceKill += int(today.Sub(start).Hours() / 24)
+		start = today
+	}
+	f := &models.Forecast{
+		ID:            info.ID,
+		Name:          info.Name,
+		World:         world,
+		DaysSinceKill: *obs.DaysSinceKill,
+		ObservedAt:    obs.UpdatedAt,
+		Confidence:    confidence(len(intervals)),
+		Days:          make([]models.ForecastDay, 0, days),
+	}
+	survival := 1.0 // Chance the boss was not killed before the day
+	for k := 0; k < forecastReach; k++ {
+		d := f.DaysSinceKill + k
+		h, _ := dailyHazard(d, intervals, r)
+		p := survival * h
+		survival -= p
+		date := start.AddDate(0, 0, k).Format(killDateLayout)
+		if k < days {
+			f.Days = append(f.Days, models.ForecastDay{
+				Date:          date,
+				DaysSinceKill: d,
+				Percent:       round1(100 * p),
+				Cumulative:    round1(100 * (1 - survival)),
+			})
+		}
+		cumulative := 1 - survival
+		if f.Earliest == nil && cumulative >= 0.05 {
+			f.Earliest = &date
+		}
+		if f.Likely == nil && cumulative >= 0.5 {
+			f.Likely = &date
+		}
+		if f.Latest == nil && cumulative >= 0.95 {
+			f.Latest = &date
+		}
+		if f.Latest != nil && k >= days {
+			break
+		}
+	}
+	return f, nil
+}
+
+func round1(v float64) float64 {
+	return math.Round(v*10) / 10
+}
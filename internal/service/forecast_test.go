@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"tibia-nemesis-api/internal/models"
+)
+
+func TestForecast(t *testing.T) {
+	svc, st, _ := newMetadataService(t, metadataV1)
+	ctx := context.Background()
+
+	// Observed three days ago at 11 days, so 14 days today. Without kill
+	// history only Furyosa's range of 12 to 46 days counts, which spreads
+	// her kill evenly over the 33 days from today to max_days.
+	observed := time.Now().UTC().AddDate(0, 0, -3)
+	scrape := []models.SpawnChance{{Name: "Furyosa", DaysSinceKill: intp(11), UpdatedAt: observed}}
+	if err := st.UpsertSpawnChances("Antica", scrape); err != nil {
+		t.Fatal(err)
+	}
+	f, err := svc.Forecast(ctx, "antica", "furyosa", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.DaysSinceKill != 14 || len(f.Days) != defaultForecastDays {
+		t.Fatalf("%d days since kill, %d days; want 14 and %d", f.DaysSinceKill, len(f.Days), defaultForecastDays)
+	}
+	today := day(time.Now())
+	date := func(k int) string { return today.AddDate(0, 0, k).Format(killDateLayout) }
+	if f.Days[0].Date != date(0) || f.Days[0].DaysSinceKill != 14 {
+		t.Errorf("forecast starts %s at %d days, want today at 14", f.Days[0].Date, f.Days[0].DaysSinceKill)
+	}
+	for k, d := range f.Days {
+		if d.Percent != 3.0 || d.Cumulative != round1(100*float64(k+1)/33) {
+			t.Errorf("day %d: %.1f%%, %.1f%% cumulative; want 3.0%%, %.1f%%", k, d.Percent, d.Cumulative, round1(100*float64(k+1)/33))
+		}
+	}
+	// 5% is passed after 2 days, 50% after 17 and 95% after 32
+	for _, tc := range []struct {
+		name string
+		got  *string
+		want string
+	}{
+		{"earliest", f.Earliest, date(1)},
+		{"likely", f.Likely, date(16)},
+		{"latest", f.Latest, date(31)},
+	} {
+		if tc.got == nil || *tc.got != tc.want {
+			t.Errorf("%s: %v, want %s", tc.name, tc.got, tc.want)
+		}
+	}
+
+	f, err = svc.Forecast(ctx, "Antica", "Furyosa", maxForecastDays)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Days) != maxForecastDays {
+		t.Errorf("%d days, want %d", len(f.Days), maxForecastDays)
+	}
+	for k, d := range f.Days {
+		if d.Cumulative > 100 {
+			t.Errorf("day %d: %.1f%% cumulative", k, d.Cumulative)
+		}
+	}
+	if last := f.Days[len(f.Days)-1]; last.Cumulative != 100 || last.Percent != 0 {
+		t.Errorf("last day: %+v, want 100%% cumulative past max_days", last)
+	}
+
+	var e *Error
+	for _, days := range []int{-1, maxForecastDays + 1} {
+		if _, err := svc.Forecast(ctx, "Antica", "Furyosa", days); !errors.As(err, &e) || e.Code != CodeValidation {
+			t.Errorf("%d days: %v, want a validation error", days, err)
+		}
+	}
+	if _, err := svc.Forecast(ctx, "Antica", "Barbaria", 0); !errors.As(err, &e) || e.Code != CodeNotFound {
+		t.Errorf("boss without observations: %v, want not_found", err)
+	}
+}
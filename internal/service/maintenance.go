@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// MaintenanceReport describes one run of the maintenance job
+type MaintenanceReport struct {
+	StartedAt       time.Time  `json:"started_at"`
+	Cutoff          *time.Time `json:"cutoff"` // Observations before it were downsampled; null when retention is disabled
+	Downsample      string     `json:"downsample,omitempty"`
+	ReclaimedRows   int64      `json:"reclaimed_rows"`
+	Observations    int64      `json:"observations"` // Left after the run
+	SizeBeforeBytes int64      `json:"size_before_bytes"`
+	SizeAfterBytes  int64      `json:"size_after_bytes"`
+	DurationMS      int64      `json:"duration_ms"`
+}
+
+// Maintain applies the retention policy to the observation history, keeping
+// full resolution for the configured number of days, then compacts the
+// database with VACUUM and ANALYZE. Kills are kept forever.
+func (s *Service) Maintain(ctx context.Context) (*MaintenanceReport, error) {
+	s.maintainMu.Lock()
+	defer s.maintainMu.Unlock()
+	start := time.Now().UTC()
+	report := &MaintenanceReport{StartedAt: start.Truncate(time.Second)}
+
+	if days := s.cfg.RetentionDays; days > 0 {
+		cutoff := day(start).AddDate(0, 0, -days)
+		n, err := s.store.DownsampleObservations(cutoff, s.cfg.RetentionDownsample)
+		if err != nil {
+			return nil, err
+		}
+		report.Cutoff = &cutoff
+		report.Downsample = s.cfg.RetentionDownsample
+		report.ReclaimedRows = n
+	}
+	var err error
+	if report.SizeBeforeBytes, report.SizeAfterBytes, err = s.store.Optimize(); err != nil {
+		return nil, err
+	}
+	if report.Observations, err = s.store.CountObservations(); err != nil {
+		return nil, err
+	}
+	report.DurationMS = time.Since(start).Milliseconds()
+	slog.InfoContext(ctx, "maintenance: complete", "removed", report.ReclaimedRows, "observations", report.Observations,
+		"size_before_bytes", report.SizeBeforeBytes, "size_after_bytes", report.SizeAfterBytes, "duration_ms", report.DurationMS)
+	return report, nil
+}
+
+// StartMaintenance runs the maintenance job at the configured interval
+func (s *Service) StartMaintenance() {
+	if s.cfg.MaintenanceInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(s.cfg.MaintenanceInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if _, err := s.Maintain(context.Background()); err != nil {
+			slog.Error("maintenance: scheduled run failed", "err", err)
+		}
+	}
+}
@@ -1,16 +1,64 @@
 package service
 
 import (
-	"log"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
 	"os"
+	"sort"
+	"strings"
+	"time"
 
 	"tibia-nemesis-api/internal/models"
+	"tibia-nemesis-api/internal/store"
 
 	"gopkg.in/yaml.v3"
 )
 
-// LoadBossMetadata loads boss metadata from YAML file
-func LoadBossMetadata(path string) (map[string]models.BossMetadata, error) {
+// MetadataErrors lists every problem found while validating a metadata file
+type MetadataErrors []string
+
+func (e MetadataErrors) Error() string {
+	return fmt.Sprintf("invalid boss metadata: %s", strings.Join(e, "; "))
+}
+
+// metadataSnapshot is an immutable, validated copy of the boss metadata.
+// It is swapped atomically on every change, so readers never see a partial update.
+type metadataSnapshot struct {
+	bosses   map[string]models.BossMetadata
+	groups   map[string][]string // World group name -> worlds
+	resolver *BossResolver
+	version  string    // Content hash of the stored metadata
+	path     string    // Metadata file imported into the store
+	modTime  time.Time // Modification time of that file when it was last checked
+	loadedAt time.Time
+}
+
+// MetadataStatus describes the active metadata
+type MetadataStatus struct {
+	Version     string    `json:"version"`
+	FileVersion string    `json:"file_version"`
+	Path        string    `json:"path"`
+	Bosses      int       `json:"bosses"`
+	Filters     int       `json:"inclusion_range_filters"`
+	LoadedAt    time.Time `json:"loaded_at"`
+}
+
+// Settings remembering the last imported metadata file
+const (
+	settingMetadataFileVersion = "metadata_file_version"
+	settingMetadataFileBosses  = "metadata_file_bosses" // The file's bosses as JSON, the base of the next import's merge
+	settingMetadataComment     = "metadata_comment"
+	settingWorldGroups         = "metadata_world_groups"
+)
+
+// LoadBossMetadata loads and validates boss metadata from a YAML file
+func LoadBossMetadata(path string) (*models.BossMetadataFile, error) {
 	if path == "" {
 		path = "bosses_metadata.yaml"
 	}
@@ -19,16 +67,475 @@ func LoadBossMetadata(path string) (map[string]models.BossMetadata, error) {
 	if err != nil {
 		return nil, err
 	}
+	return ParseBossMetadata(data)
+}
 
+// ParseBossMetadata decodes a metadata file strictly (unknown fields and duplicate
+// keys are rejected) and validates it. IDs default to the slug of the name.
+func ParseBossMetadata(data []byte) (*models.BossMetadataFile, error) {
 	var file models.BossMetadataFile
-	if err := yaml.Unmarshal(data, &file); err != nil {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&file); err != nil {
+		return nil, MetadataErrors{err.Error()}
+	}
+
+	for name, meta := range file.Bosses {
+		if meta.ID == "" {
+			meta.ID = Slug(name)
+			file.Bosses[name] = meta
+		}
+	}
+	if problems := ValidateBossMetadata(file.Bosses, file.WorldGroups); len(problems) > 0 {
+		return nil, problems
+	}
+	return &file, nil
+}
+
+// ValidateBossMetadata checks ranges, key/name consistency, that IDs and aliases
+// are unique and that overrides target existing world groups
+func ValidateBossMetadata(bosses map[string]models.BossMetadata, groups map[string][]string) MetadataErrors {
+	var problems MetadataErrors
+	groupNames := make([]string, 0, len(groups))
+	for group := range groups {
+		groupNames = append(groupNames, group)
+	}
+	sort.Strings(groupNames)
+	for _, group := range groupNames {
+		if strings.TrimSpace(group) == "" {
+			problems = append(problems, "world_groups: group name is required")
+		}
+		if len(groups[group]) == 0 {
+			problems = append(problems, fmt.Sprintf("world_groups: %s lists no worlds", group))
+		}
+	}
+
+	keys := make(map[string]string) // name, ID or alias -> owning boss
+	// Keys are claimed in both forms the resolver looks up
+	claim := func(key, owner, what string) {
+		for _, k := range []string{strings.ToLower(strings.TrimSpace(key)), Slug(key)} {
+			if other, ok := keys[k]; ok && other != owner {
+				problems = append(problems, fmt.Sprintf("%s: %s %q is already used by %s", owner, what, key, other))
+				return
+			}
+			keys[k] = owner
+		}
+	}
+
+	names := make([]string, 0, len(bosses))
+	for name := range bosses {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		meta := bosses[name]
+		if strings.TrimSpace(meta.Name) == "" {
+			problems = append(problems, fmt.Sprintf("%s: name is required", name))
+		} else if meta.Name != name {
+			problems = append(problems, fmt.Sprintf("%s: name %q does not match its key", name, meta.Name))
+		}
+		problems = append(problems, validateRange(name, meta.InclusionRange)...)
+		for i, o := range meta.Overrides {
+			where := fmt.Sprintf("%s: overrides[%d]", name, i)
+			switch {
+			case len(o.Worlds) > 0 && o.Group != "":
+				problems = append(problems, where+": set either worlds or group, not both")
+			case len(o.Worlds) == 0 && o.Group == "":
+				problems = append(problems, where+": worlds or group is required")
+			case o.Group != "" && groups[o.Group] == nil:
+				problems = append(problems, fmt.Sprintf("%s: unknown world group %q", where, o.Group))
+			}
+			if o.InclusionRange == nil && o.Hidden == nil {
+				problems = append(problems, where+": inclusion_range or hidden is required")
+			}
+			problems = append(problems, validateRange(where, o.InclusionRange)...)
+		}
+		if c := meta.Location; c != nil && c.Coordinates != nil && (c.Coordinates.Z < 0 || c.Coordinates.Z > 15) {
+			problems = append(problems, fmt.Sprintf("%s: location z must be between 0 and 15", name))
+		}
+		claim(name, name, "name")
+		if meta.ID != Slug(meta.ID) {
+			problems = append(problems, fmt.Sprintf("%s: id %q must be a lower-case slug", name, meta.ID))
+		}
+		claim(meta.ID, name, "id")
+		for _, alias := range meta.Aliases {
+			claim(alias, name, "alias")
+		}
+	}
+	return problems
+}
+
+func validateRange(where string, r *models.InclusionRange) MetadataErrors {
+	var problems MetadataErrors
+	if r == nil {
+		return nil
+	}
+	if r.MinDays < 0 || r.MaxDays < 0 {
+		problems = append(problems, fmt.Sprintf("%s: inclusion_range days must be non-negative", where))
+	}
+	if r.MinDays > r.MaxDays {
+		problems = append(problems, fmt.Sprintf("%s: inclusion_range min_days %d is greater than max_days %d", where, r.MinDays, r.MaxDays))
+	}
+	return problems
+}
+
+// metadata returns the active metadata snapshot
+func (s *Service) metadata() *metadataSnapshot {
+	return s.meta.Load()
+}
+
+// importMetadataFile imports the metadata file at path into the store when
+// its content changed since the last import, then activates the stored
+// metadata. The import is a three-way merge against the last imported file,
+// so edits made through the admin API since then are kept; see
+// mergeMetadata. An invalid or conflicting file is refused and the active
+// snapshot is left untouched.
+func (s *Service) importMetadataFile(path string) error {
+	s.metaMu.Lock()
+	defer s.metaMu.Unlock()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	version := contentVersion(data)
+	last, err := s.store.GetSetting(settingMetadataFileVersion)
+	if err != nil {
+		return err
+	}
+	if version != last {
+		file, err := ParseBossMetadata(data)
+		if err != nil {
+			return err
+		}
+		stored, err := s.store.ListBossMetadata()
+		if err != nil {
+			return err
+		}
+		base, err := s.lastImportedBosses(stored, file.Bosses)
+		if err != nil {
+			return err
+		}
+		changes, conflicts := mergeMetadata(base, stored, file.Bosses)
+		if len(conflicts) > 0 {
+			return conflicts
+		}
+		merged := applyChanges(stored, changes)
+		if problems := ValidateBossMetadata(merged, file.WorldGroups); len(problems) > 0 {
+			return problems
+		}
+		if err := s.store.ApplyMetadataChanges("file:"+path, changes); err != nil {
+			return err
+		}
+		doc, err := json.Marshal(file.Bosses)
+		if err != nil {
+			return err
+		}
+		if err := s.store.SetSetting(settingMetadataFileBosses, string(doc)); err != nil {
+			return err
+		}
+		if err := s.store.SetSetting(settingMetadataComment, file.Comment); err != nil {
+			return err
+		}
+		groups, err := json.Marshal(file.WorldGroups)
+		if err != nil {
+			return err
+		}
+		if err := s.store.SetSetting(settingWorldGroups, string(groups)); err != nil {
+			return err
+		}
+		if err := s.store.SetSetting(settingMetadataFileVersion, version); err != nil {
+			return err
+		}
+		slog.Info("metadata: imported file", "path", path, "file_version", version, "changed", len(changes))
+	}
+	return s.activateStoredMetadata(path, info.ModTime())
+}
+
+// activateStoredMetadata builds a snapshot from the store and makes it active.
+// Callers hold metaMu.
+func (s *Service) activateStoredMetadata(path string, modTime time.Time) error {
+	stored, err := StoredMetadata(s.store)
+	if err != nil {
+		return err
+	}
+	bosses, groups := stored.Bosses, stored.WorldGroups
+	// Documents are marshalled with sorted map keys, so equal sets hash equally
+	doc, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+	version := contentVersion(doc)
+	if cur := s.metadata(); cur != nil && cur.version == version {
+		s.meta.Store(&metadataSnapshot{
+			bosses: cur.bosses, groups: cur.groups, resolver: cur.resolver, version: version,
+			path: path, modTime: modTime, loadedAt: cur.loadedAt,
+		})
+		return nil
+	}
+	s.meta.Store(&metadataSnapshot{
+		bosses:   bosses,
+		groups:   groups,
+		resolver: NewBossResolver(bosses),
+		version:  version,
+		path:     path,
+		modTime:  modTime,
+		loadedAt: time.Now().UTC(),
+	})
+	slog.Info("metadata: loaded", "version", version, "bosses", len(bosses), "inclusion_range_filters", countWithFilters(bosses))
+	return nil
+}
+
+// StoredMetadata reads the boss metadata and world groups kept in the store
+func StoredMetadata(st *store.SQLite) (*models.BossMetadataFile, error) {
+	bosses, err := st.ListBossMetadata()
+	if err != nil {
 		return nil, err
 	}
+	file := &models.BossMetadataFile{Bosses: bosses}
+	if raw, err := st.GetSetting(settingWorldGroups); err != nil {
+		return nil, err
+	} else if raw != "" {
+		if err := json.Unmarshal([]byte(raw), &file.WorldGroups); err != nil {
+			return nil, err
+		}
+	}
+	return file, nil
+}
+
+// lastImportedBosses returns the bosses of the last imported file. Databases
+// that don't have them recorded yet are seeded from the file when they hold
+// no metadata; otherwise the stored metadata is kept as it is and the file
+// is only merged from its next change on.
+func (s *Service) lastImportedBosses(stored, file map[string]models.BossMetadata) (map[string]models.BossMetadata, error) {
+	raw, err := s.store.GetSetting(settingMetadataFileBosses)
+	if err != nil {
+		return nil, err
+	}
+	if raw != "" {
+		var base map[string]models.BossMetadata
+		err := json.Unmarshal([]byte(raw), &base)
+		return base, err
+	}
+	if len(stored) == 0 {
+		return nil, nil
+	}
+	slog.Warn("metadata: no record of the last imported file, keeping the stored metadata", "bosses", len(stored))
+	return file, nil
+}
+
+// mergeMetadata lists the changes that bring the file's edits since base,
+// the last imported file, into the stored metadata. Bosses only changed in
+// the file take the file's version; bosses only changed in the store (through
+// the admin API) keep theirs. A boss changed differently on both sides is a
+// conflict, and nothing should be applied.
+func mergeMetadata(base, stored, next map[string]models.BossMetadata) ([]store.MetadataChange, MetadataErrors) {
+	names := make(map[string]bool)
+	for _, m := range []map[string]models.BossMetadata{base, stored, next} {
+		for name := range m {
+			names[name] = true
+		}
+	}
+	var changes []store.MetadataChange
+	var conflicts MetadataErrors
+	for name := range names {
+		b, inBase := base[name]
+		cur, inStore := stored[name]
+		n, inNext := next[name]
+		if sameMetadata(b, inBase, n, inNext) || sameMetadata(cur, inStore, n, inNext) {
+			continue // Unchanged in the file, or already what the file says
+		}
+		if !sameMetadata(b, inBase, cur, inStore) {
+			conflicts = append(conflicts, fmt.Sprintf("%s: changed both in the file and through the admin API since the last import", name))
+			continue
+		}
+		var c store.MetadataChange
+		if inStore {
+			c.Before = &cur
+		}
+		if inNext {
+			c.After = &n
+		}
+		changes = append(changes, c)
+	}
+	sort.Slice(changes, func(i, j int) bool { return changeName(changes[i]) < changeName(changes[j]) })
+	sort.Strings(conflicts)
+	return changes, conflicts
+}
+
+// sameMetadata reports whether two possibly absent bosses are equal. They are
+// compared as JSON, like they are stored, so empty and nil lists are equal.
+func sameMetadata(a models.BossMetadata, hasA bool, b models.BossMetadata, hasB bool) bool {
+	if !hasA || !hasB {
+		return hasA == hasB
+	}
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(ja, jb)
+}
+
+// applyChanges returns a copy of bosses with changes applied
+func applyChanges(bosses map[string]models.BossMetadata, changes []store.MetadataChange) map[string]models.BossMetadata {
+	out := make(map[string]models.BossMetadata, len(bosses))
+	for name, meta := range bosses {
+		out[name] = meta
+	}
+	for _, c := range changes {
+		if c.After == nil {
+			delete(out, c.Before.Name)
+		} else {
+			out[c.After.Name] = *c.After
+		}
+	}
+	return out
+}
+
+func changeName(c store.MetadataChange) string {
+	if c.After != nil {
+		return c.After.Name
+	}
+	return c.Before.Name
+}
+
+func contentVersion(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// ReloadMetadata re-imports the metadata file. Invalid files are refused and
+// the last good metadata stays active.
+func (s *Service) ReloadMetadata(ctx context.Context) (*MetadataStatus, error) {
+	if err := s.importMetadataFile(s.cfg.MetadataPath); err != nil {
+		slog.WarnContext(ctx, "metadata: reload refused", "keeping", s.metadata().version, "err", err)
+		var problems MetadataErrors
+		if errors.As(err, &problems) {
+			return nil, &Error{
+				Code:    CodeValidation,
+				Message: "metadata file rejected, previous metadata kept",
+				Details: map[string]any{"problems": []string(problems)},
+				Err:     err,
+			}
+		}
+		return nil, err
+	}
+	status := s.MetadataStatus()
+	return &status, nil
+}
+
+// StartMetadataWatcher re-imports the metadata whenever its file changes
+func (s *Service) StartMetadataWatcher() {
+	if s.cfg.MetadataWatchInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(s.cfg.MetadataWatchInterval)
+	defer ticker.Stop()
+	var refused time.Time // modTime of the last refused file, so it is not retried every tick
+	for range ticker.C {
+		info, err := os.Stat(s.cfg.MetadataPath)
+		if err != nil {
+			continue
+		}
+		cur := s.metadata()
+		if info.ModTime().Equal(cur.modTime) || info.ModTime().Equal(refused) {
+			continue
+		}
+		if err := s.importMetadataFile(s.cfg.MetadataPath); err != nil {
+			slog.Warn("metadata: watcher refused changed file", "path", s.cfg.MetadataPath, "keeping", cur.version, "err", err)
+			refused = info.ModTime()
+		}
+	}
+}
+
+func (s *Service) MetadataStatus() MetadataStatus {
+	m := s.metadata()
+	fileVersion, err := s.store.GetSetting(settingMetadataFileVersion)
+	if err != nil {
+		slog.Error("metadata: status", "err", err)
+	}
+	return MetadataStatus{
+		Version:     m.version,
+		FileVersion: fileVersion,
+		Path:        m.path,
+		Bosses:      len(m.bosses),
+		Filters:     countWithFilters(m.bosses),
+		LoadedAt:    m.loadedAt,
+	}
+}
+
+// BossMetadata returns the metadata of every boss, sorted by name
+func (s *Service) BossMetadata(ctx context.Context) []models.BossMetadata {
+	bosses := s.metadata().bosses
+	out := make([]models.BossMetadata, 0, len(bosses))
+	for _, meta := range bosses {
+		out = append(out, meta)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
 
-	log.Printf("Loaded metadata for %d bosses (%d with inclusion_range filters)",
-		len(file.Bosses), countWithFilters(file.Bosses))
+// forWorld returns the metadata in effect on world, with every boss's
+// overrides applied. Precedence: an override listing the world, then an
+// override for a group containing it (the first matching one), then the
+// boss's global values.
+func (m *metadataSnapshot) forWorld(world string) map[string]models.BossMetadata {
+	out := make(map[string]models.BossMetadata, len(m.bosses))
+	for name, meta := range m.bosses {
+		if len(meta.Overrides) > 0 {
+			meta = applyOverride(meta, m.matchOverride(meta.Overrides, world))
+		}
+		out[name] = meta
+	}
+	return out
+}
+
+func (m *metadataSnapshot) matchOverride(overrides []models.MetadataOverride, world string) *models.MetadataOverride {
+	var group *models.MetadataOverride
+	for i, o := range overrides {
+		for _, w := range o.Worlds {
+			if strings.EqualFold(w, world) {
+				return &overrides[i]
+			}
+		}
+		if group == nil && o.Group != "" && containsFold(m.groups[o.Group], world) {
+			group = &overrides[i]
+		}
+	}
+	return group
+}
 
-	return file.Bosses, nil
+func applyOverride(meta models.BossMetadata, o *models.MetadataOverride) models.BossMetadata {
+	if o == nil {
+		return meta
+	}
+	if o.InclusionRange != nil {
+		meta.InclusionRange = o.InclusionRange
+	}
+	if o.Hidden != nil {
+		meta.Hidden = *o.Hidden
+	}
+	return meta
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// WorldGroups returns the world groups overrides can target
+func (s *Service) WorldGroups(ctx context.Context) map[string][]string {
+	if groups := s.metadata().groups; groups != nil {
+		return groups
+	}
+	return map[string][]string{}
 }
 
 func countWithFilters(bosses map[string]models.BossMetadata) int {
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"tibia-nemesis-api/internal/config"
+	"tibia-nemesis-api/internal/models"
+	"tibia-nemesis-api/internal/store"
+)
+
+const metadataV1 = `bosses:
+  Furyosa:
+    name: Furyosa
+    inclusion_range: {min_days: 12, max_days: 46}
+  Barbaria:
+    name: Barbaria
+    note: Ice Islands
+  Albino Dragon:
+    name: Albino Dragon
+`
+
+// newMetadataService starts a service on a new database seeded from a
+// metadata file with the given content
+func newMetadataService(t *testing.T, content string) (*Service, *store.SQLite, config.Config) {
+	t.Helper()
+	dir := t.TempDir()
+	cfg := config.Defaults()
+	cfg.MetadataPath = filepath.Join(dir, "bosses_metadata.yaml")
+	writeMetadata(t, cfg, content)
+	st, err := store.NewSQLite(filepath.Join(dir, "meta.db"), store.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { st.Close() })
+	svc, err := New(st, nil, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return svc, st, cfg
+}
+
+func writeMetadata(t *testing.T, cfg config.Config, content string) {
+	t.Helper()
+	if err := os.WriteFile(cfg.MetadataPath, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMetadataImportKeepsAdminEdits(t *testing.T) {
+	svc, _, cfg := newMetadataService(t, metadataV1)
+	ctx := context.Background()
+
+	// Admin edits: a changed range, a new boss
+	_, err := svc.UpdateBossMetadata(ctx, "alice", "Furyosa", models.BossMetadata{
+		Name: "Furyosa", InclusionRange: &models.InclusionRange{MinDays: 10, MaxDays: 40},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.CreateBossMetadata(ctx, "alice", models.BossMetadata{Name: "Ferumbras"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The file changes other bosses: one edited, one removed, one added
+	writeMetadata(t, cfg, `bosses:
+  Furyosa:
+    name: Furyosa
+    inclusion_range: {min_days: 12, max_days: 46}
+  Barbaria:
+    name: Barbaria
+    note: Ice Islands, north
+  Morshabaal:
+    name: Morshabaal
+`)
+	if _, err := svc.ReloadMetadata(ctx); err != nil {
+		t.Fatal(err)
+	}
+	bosses := svc.metadata().bosses
+	if r := bosses["Furyosa"].InclusionRange; r == nil || r.MinDays != 10 || r.MaxDays != 40 {
+		t.Errorf("Furyosa's admin edit was not kept: %+v", r)
+	}
+	if _, ok := bosses["Ferumbras"]; !ok {
+		t.Error("boss created through the admin API was deleted")
+	}
+	if bosses["Barbaria"].Note != "Ice Islands, north" {
+		t.Errorf("Barbaria's file edit was not imported: %q", bosses["Barbaria"].Note)
+	}
+	if _, ok := bosses["Albino Dragon"]; ok {
+		t.Error("boss removed from the file was kept")
+	}
+	if _, ok := bosses["Morshabaal"]; !ok {
+		t.Error("boss added to the file was not imported")
+	}
+}
+
+func TestMetadataImportRefusesConflicts(t *testing.T) {
+	svc, _, cfg := newMetadataService(t, metadataV1)
+	ctx := context.Background()
+	_, err := svc.UpdateBossMetadata(ctx, "alice", "Barbaria", models.BossMetadata{Name: "Barbaria", Note: "From the API"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Barbaria conflicts; Furyosa's change alone would merge but is not applied either
+	writeMetadata(t, cfg, `bosses:
+  Furyosa:
+    name: Furyosa
+  Barbaria:
+    name: Barbaria
+    note: From the file
+`)
+	_, err = svc.ReloadMetadata(ctx)
+	var e *Error
+	if !errors.As(err, &e) || e.Code != CodeValidation {
+		t.Fatalf("conflicting reload returned %v, want a validation error", err)
+	}
+	bosses := svc.metadata().bosses
+	if bosses["Barbaria"].Note != "From the API" || bosses["Furyosa"].InclusionRange == nil || len(bosses) != 3 {
+		t.Errorf("a refused import changed the metadata: %+v", bosses)
+	}
+}
+
+func TestInvalidMetadataFileAtStartup(t *testing.T) {
+	svc, st, cfg := newMetadataService(t, metadataV1)
+	version := svc.metadata().version
+	writeMetadata(t, cfg, "bosses:\n  Furyosa:\n    name: Someone else\n")
+
+	// The stored metadata is served
+	svc, err := New(st, nil, cfg)
+	if err != nil {
+		t.Fatalf("startup failed with stored metadata to fall back to: %v", err)
+	}
+	if svc.metadata().version != version {
+		t.Errorf("serving metadata %s, want the stored %s", svc.metadata().version, version)
+	}
+
+	// Without stored metadata there is nothing to serve
+	empty, err := store.NewSQLite(filepath.Join(t.TempDir(), "empty.db"), store.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer empty.Close()
+	if _, err := New(empty, nil, cfg); err == nil {
+		t.Error("startup with an invalid file and no stored metadata succeeded")
+	}
+}
@@ -0,0 +1,26 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"tibia-nemesis-api/internal/metrics"
+)
+
+var (
+	nextRefresh = metrics.NewGauge("tibia_nemesis_scheduler_next_run_timestamp_seconds", "Unix time of the next scheduled refresh")
+	dataAge     = metrics.NewGauge("tibia_nemesis_data_age_seconds", "Seconds since the spawn chances of a world were last scraped", "world")
+)
+
+// CollectMetrics updates the metrics that are computed when they are read
+func (s *Service) CollectMetrics(ctx context.Context) error {
+	updated, err := s.store.LastUpdated()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for world, t := range updated {
+		dataAge.Set(now.Sub(t).Seconds(), world)
+	}
+	return nil
+}
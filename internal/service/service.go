@@ -3,101 +3,200 @@ package service
 import (
 	"context"
 	"errors"
-	"log"
-	"strings"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"tibia-nemesis-api/internal/config"
+	"tibia-nemesis-api/internal/logging"
 	"tibia-nemesis-api/internal/models"
+	"tibia-nemesis-api/internal/notify"
 	"tibia-nemesis-api/internal/scraper"
 	"tibia-nemesis-api/internal/store"
+	"tibia-nemesis-api/internal/webhook"
 )
 
 type Service struct {
-	store    *store.SQLite
-	scraper  scraper.Scraper
-	cfg      config.Config
-	metadata map[string]models.BossMetadata
+	store      *store.SQLite
+	scraper    scraper.Scraper
+	cfg        config.Config
+	tz         *time.Location // Scheduler time zone
+	refreshAt  [2]int         // Hour and minute of the daily refresh, in tz
+	meta       atomic.Pointer[metadataSnapshot]
+	metaMu     sync.Mutex // Serializes metadata imports and edits
+	backupMu   sync.Mutex // Serializes snapshots and their rotation
+	maintainMu sync.Mutex // Serializes maintenance runs
+	webhooks   *webhook.Dispatcher
+	discord    *notify.Discord
+	worlds     *WorldRegistry
+	analysis   atomic.Pointer[models.InclusionRangeAnalysis]
+	kills      atomic.Pointer[map[string]*bossKills] // Kill history by boss, refreshed with the analysis
+	calendars  calendarCache
 }
 
-func New(st *store.SQLite, sc scraper.Scraper, cfg config.Config) *Service {
-	svc := &Service{store: st, scraper: sc, cfg: cfg}
+func New(st *store.SQLite, sc scraper.Scraper, cfg config.Config) (*Service, error) {
+	tz, err := cfg.Location()
+	if err != nil {
+		return nil, err
+	}
+	hour, minute, err := cfg.RefreshClock()
+	if err != nil {
+		return nil, err
+	}
+	webhooks := webhook.NewDispatcher(st, webhook.Options{
+		MaxAttempts: cfg.WebhookMaxAttempts,
+		Backoff:     cfg.WebhookBackoff,
+		MaxBackoff:  cfg.WebhookMaxBackoff,
+		Timeout:     cfg.WebhookTimeout,
+	})
+	// Worlds with stored data stay valid when the built-in list or WORLDS
+	// lacks them
+	stored, err := st.GetWorlds()
+	if err != nil {
+		return nil, fmt.Errorf("worlds: %w", err)
+	}
+	worlds := NewWorldRegistry(cfg.Worlds)
+	worlds.Add(stored...)
+	svc := &Service{
+		store:     st,
+		scraper:   sc,
+		cfg:       cfg,
+		tz:        tz,
+		refreshAt: [2]int{hour, minute},
+		webhooks:  webhooks,
+		discord:   notify.NewDiscord(cfg.DiscordHighChance),
+		worlds:    worlds,
+	}
 
-	// Load boss metadata for inclusion_range filtering
-	if meta, err := LoadBossMetadata("bosses_metadata.yaml"); err != nil {
-		log.Printf("Warning: Failed to load boss metadata: %v (filtering disabled)", err)
-	} else {
-		svc.metadata = meta
+	// Seed the stored boss metadata from the metadata file, merging it in when
+	// the file changed. Without a file, or with one that is refused, the
+	// stored metadata is served as is. A refused file is only a startup error
+	// when there is no stored metadata to fall back to.
+	if err := svc.importMetadataFile(cfg.MetadataPath); err != nil {
+		svc.metaMu.Lock()
+		activateErr := svc.activateStoredMetadata(cfg.MetadataPath, time.Time{})
+		svc.metaMu.Unlock()
+		if activateErr != nil {
+			return nil, fmt.Errorf("boss metadata: %w", activateErr)
+		}
+		missing := errors.Is(err, fs.ErrNotExist)
+		stored := len(svc.metadata().bosses)
+		switch {
+		case !missing && stored == 0:
+			return nil, fmt.Errorf("boss metadata %s: %w", cfg.MetadataPath, err)
+		case !missing:
+			slog.Error("metadata: file refused, serving the stored metadata", "path", cfg.MetadataPath,
+				"version", svc.metadata().version, "bosses", stored, "err", err)
+		case stored == 0:
+			slog.Warn("boss metadata not found, filtering disabled", "path", cfg.MetadataPath)
+		}
 	}
 
-	return svc
+	return svc, nil
 } // StartScheduler performs a daily refresh at configured time.
 func (s *Service) StartScheduler() {
-	log.Printf("Scheduler started. Next refresh at: %v", s.nextRun())
+	slog.Info("scheduler: started", "next_run", s.nextRun())
 	for {
 		next := s.nextRun()
+		nextRefresh.Set(float64(next.Unix()))
 		d := time.Until(next)
-		log.Printf("Scheduler: sleeping until %v (in %v)", next, d)
+		slog.Info("scheduler: sleeping", "until", next, "in", d.Round(time.Second).String())
 		if d > 0 {
 			time.Sleep(d)
 		}
 
-		log.Printf("Scheduler: starting automatic refresh")
-		// In absence of configured worlds list, refresh the worlds we already know
-		worlds, err := s.store.GetWorlds()
-		if err != nil {
-			log.Printf("scheduled refresh: failed to get worlds: %v", err)
-			continue
-		}
-
-		if len(worlds) == 0 {
-			log.Printf("scheduled refresh: no worlds in database to refresh")
-			continue
-		}
-
-		log.Printf("Scheduler: refreshing %d worlds: %v", len(worlds), worlds)
-		for _, w := range worlds {
-			log.Printf("Scheduler: refreshing world %s", w)
-			if err := s.RefreshWorld(context.Background(), w); err != nil {
-				log.Printf("scheduled refresh %s: %v", w, err)
-			} else {
-				log.Printf("Scheduler: successfully refreshed %s", w)
-			}
+		slog.Info("scheduler: starting automatic refresh")
+		if err := s.RefreshAll(context.Background()); err != nil {
+			slog.Error("scheduler: refresh failed", "err", err)
 		}
-		log.Printf("Scheduler: refresh complete")
 	}
 }
 
-func (s *Service) nextRun() time.Time {
-	tz, err := time.LoadLocation(s.cfg.TZ)
+// RefreshAll refreshes every world already in the database, posts their
+// digests and recomputes the kill analysis. Worlds that fail are logged and
+// returned together; the others are still refreshed. The run's log lines
+// share a run ID.
+func (s *Service) RefreshAll(ctx context.Context) error {
+	ctx = logging.WithRunID(ctx)
+	// In absence of configured worlds list, refresh the worlds we already know
+	worlds, err := s.store.GetWorlds()
 	if err != nil {
-		tz = time.Local
+		return fmt.Errorf("failed to get worlds: %w", err)
+	}
+	if len(worlds) == 0 {
+		return errors.New("no worlds in database to refresh")
 	}
-	now := time.Now().In(tz)
-	parts := strings.SplitN(s.cfg.RefreshAt, ":", 2)
-	hour, min := 9, 0
-	if len(parts) == 2 {
-		if v, err := time.Parse("15:04", s.cfg.RefreshAt); err == nil {
-			hour, min = v.Hour(), v.Minute()
+
+	slog.InfoContext(ctx, "refresh: starting run", "worlds", worlds)
+	var failed []error
+	for _, w := range worlds {
+		if err := s.RefreshWorld(ctx, w); err != nil {
+			slog.ErrorContext(ctx, "refresh: world failed", "world", w, "err", err)
+			failed = append(failed, fmt.Errorf("%s: %w", w, err))
+		} else {
+			s.postDigest(ctx, w)
 		}
 	}
-	run := time.Date(now.Year(), now.Month(), now.Day(), hour, min, 0, 0, tz)
+	slog.InfoContext(ctx, "refresh: run complete", "worlds", len(worlds), "failed", len(failed))
+	if _, err := s.refreshAnalysis(); err != nil {
+		slog.ErrorContext(ctx, "refresh: analysis failed", "err", err)
+	}
+	return errors.Join(failed...)
+}
+
+// location returns the configured scheduler time zone
+func (s *Service) location() *time.Location {
+	return s.tz
+}
+
+func (s *Service) nextRun() time.Time {
+	now := time.Now().In(s.tz)
+	run := time.Date(now.Year(), now.Month(), now.Day(), s.refreshAt[0], s.refreshAt[1], 0, 0, s.tz)
 	if !run.After(now) {
 		run = run.Add(24 * time.Hour)
 	}
 	return run
 }
 
+// RefreshWorld scrapes a world and records the result. Its log lines carry
+// the run ID of ctx, or a new one. A world that is not registered is looked
+// up at the data source and registered when it has data there.
 func (s *Service) RefreshWorld(ctx context.Context, world string) error {
-	if world == "" {
-		return errors.New("world required")
+	name, err := s.canonicalWorld(world)
+	if err != nil {
+		var e *Error
+		if !errors.As(err, &e) || e.Code != CodeUnknownWorld {
+			return err
+		}
+		var ok bool
+		if name, ok = newWorldName(world); !ok {
+			return err
+		}
 	}
-	list, err := s.scraper.Fetch(world)
+	world = name
+	ctx = logging.WithRunID(ctx)
+	start := time.Now()
+	list, err := s.scraper.Fetch(ctx, world)
 	if err != nil {
-		return err
+		return upstreamError(world, err)
+	}
+	if _, known := s.worlds.Canonical(world); !known {
+		if len(list) == 0 {
+			return unknownWorld(world)
+		}
+		s.worlds.Add(world)
+		slog.InfoContext(ctx, "refresh: new world registered", "world", world)
 	}
 	// Additional logic hook: clamp percent to [0, 100], round, etc.
 	for i := range list {
+		if name, ok := s.resolveBoss(list[i].Name); ok {
+			list[i].Name = name
+		} else if len(s.metadata().bosses) > 0 {
+			slog.WarnContext(ctx, "refresh: unmatched boss name, add it to bosses_metadata.yaml or as an alias", "world", world, "boss", list[i].Name)
+		}
 		if list[i].Percent != nil {
 			v := *list[i].Percent
 			if v < 0 {
@@ -110,45 +209,55 @@ func (s *Service) RefreshWorld(ctx context.Context, world string) error {
 		}
 		list[i].UpdatedAt = time.Now().UTC()
 	}
-	return s.store.UpsertSpawnChances(world, list)
+	prev, err := s.store.GetSpawnChances(world)
+	if err != nil {
+		return err
+	}
+	if err := s.store.UpsertSpawnChances(world, list); err != nil {
+		return err
+	}
+	s.notifyRefresh(ctx, world, prev, list)
+	s.evaluateWatches(ctx, world)
+	if _, err := s.refreshCalendar(ctx, world); err != nil {
+		slog.ErrorContext(ctx, "refresh: calendar failed", "world", world, "err", err)
+	}
+	slog.InfoContext(ctx, "refresh: world refreshed", "world", world, "bosses", len(list), "duration_ms", time.Since(start).Milliseconds())
+	return nil
 }
 
-// Bosses returns all bosses with their spawnable status
+// Bosses returns all bosses with their spawnable status. A known world that
+// was not scraped yet lists the metadata bosses without chances.
 func (s *Service) Bosses(ctx context.Context, world string) (*models.BossesResponse, error) {
+	world, err := s.canonicalWorld(world)
+	if err != nil {
+		return nil, err
+	}
+	metadata := s.metadata().forWorld(world)
 	allChances, err := s.store.GetSpawnChances(world)
 	if err != nil {
 		return nil, err
 	}
 
-	// Create map of existing bosses from database
-	// Use lower-case for all keys for case-insensitive matching
+	// Key bosses by canonical name so scraped variants and aliases merge into one entry
 	existingBosses := make(map[string]models.SpawnChance)
 	missingFromDB := make(map[string]bool) // Track bosses added from metadata
-	nameMap := make(map[string]string)     // lower-case name -> metadata name
 	latestUpdate := time.Now().UTC()
 
-	// Build nameMap from metadata
-	for metaName := range s.metadata {
-		nameMap[strings.ToLower(metaName)] = metaName
-	}
-
 	for _, chance := range allChances {
-		lc := strings.ToLower(chance.Name)
-		// If metadata has this boss, use metadata casing
-		if metaName, ok := nameMap[lc]; ok {
-			chance.Name = metaName
+		chance.Name, _ = s.resolveBoss(chance.Name)
+		if prev, ok := existingBosses[chance.Name]; ok && prev.UpdatedAt.After(chance.UpdatedAt) {
+			continue
 		}
-		existingBosses[lc] = chance
+		existingBosses[chance.Name] = chance
 		if chance.UpdatedAt.After(latestUpdate) {
 			latestUpdate = chance.UpdatedAt
 		}
 	}
 
 	// Add metadata bosses that aren't in the database yet
-	for metaName := range s.metadata {
-		lc := strings.ToLower(metaName)
-		if _, exists := existingBosses[lc]; !exists {
-			existingBosses[lc] = models.SpawnChance{
+	for metaName := range metadata {
+		if _, exists := existingBosses[metaName]; !exists {
+			existingBosses[metaName] = models.SpawnChance{
 				World:         world,
 				Name:          metaName,
 				Percent:       nil,
@@ -156,42 +265,63 @@ func (s *Service) Bosses(ctx context.Context, world string) (*models.BossesRespo
 				IsNoChance:    false,
 				UpdatedAt:     time.Now().UTC(),
 			}
-			missingFromDB[lc] = true // Mark as missing from DB (spawnable by default)
+			missingFromDB[metaName] = true // Mark as missing from DB (spawnable by default)
 		}
 	}
 
-	// Convert map back to slice
+	// Convert map back to slice, leaving out bosses hidden on this world
 	allBosses := make([]models.SpawnChance, 0, len(existingBosses))
 	for _, boss := range existingBosses {
+		if metadata[boss.Name].Hidden {
+			continue
+		}
 		allBosses = append(allBosses, boss)
 	}
 
 	// Get spawnables (filtered list)
 	spawnableChances := allBosses
-	if len(s.metadata) > 0 {
-		spawnableChances = ApplyInclusionRange(allBosses, s.metadata)
+	if len(metadata) > 0 {
+		spawnableChances = ApplyInclusionRange(allBosses, metadata)
 	}
 
 	// Create a map for quick lookup
 	spawnableMap := make(map[string]bool)
 	for _, sc := range spawnableChances {
-		spawnableMap[strings.ToLower(sc.Name)] = true
+		spawnableMap[sc.Name] = true
 	}
 
 	// Build response with all bosses
 	bosses := make([]models.BossInfo, 0, len(allBosses))
+	history := s.killIntervals()
 
 	for _, chance := range allBosses {
-		lc := strings.ToLower(chance.Name)
 		// Bosses not in DB (missing from tibia-statistic) are spawnable by default
-		spawnable := spawnableMap[lc] || missingFromDB[lc]
+		spawnable := spawnableMap[chance.Name] || missingFromDB[chance.Name]
 
-		bosses = append(bosses, models.BossInfo{
+		info := models.BossInfo{
+			ID:            s.bossID(chance.Name),
 			Name:          chance.Name,
 			Percent:       chance.Percent,
 			DaysSinceKill: chance.DaysSinceKill,
 			Spawnable:     spawnable,
-		})
+		}
+		var intervals []int
+		if h := history[chance.Name]; h != nil {
+			intervals = h.intervals
+		}
+		if est := estimateSpawn(chance.DaysSinceKill, intervals, metadata[chance.Name].InclusionRange); est != nil {
+			info.EstimatedPercent = &est.Percent
+			info.Confidence = est.Confidence
+		}
+		if meta, ok := metadata[chance.Name]; ok {
+			info.Category = meta.Category
+			info.Location = meta.Location
+			info.MultiSpawn = meta.MultiSpawn
+			info.EventOnly = meta.EventOnly
+			info.WikiURL = meta.WikiURL
+			info.Note = meta.Note
+		}
+		bosses = append(bosses, info)
 	}
 
 	return &models.BossesResponse{
@@ -201,10 +331,46 @@ func (s *Service) Bosses(ctx context.Context, world string) (*models.BossesRespo
 	}, nil
 }
 
+// CanonicalWorld returns the registered casing of world, or an unknown_world error
+func (s *Service) CanonicalWorld(world string) (string, error) {
+	return s.canonicalWorld(world)
+}
+
 func (s *Service) Worlds(ctx context.Context) ([]string, error) {
 	return s.store.GetWorlds()
 }
 
+// Boss returns one boss of a world, looked up by name, ID or alias
+func (s *Service) Boss(ctx context.Context, world, name string) (*models.BossInfo, error) {
+	resp, err := s.Bosses(ctx, world)
+	if err != nil {
+		return nil, err
+	}
+	canonical, _ := s.resolveBoss(name)
+	for i := range resp.Bosses {
+		b := &resp.Bosses[i]
+		if b.Name == canonical || b.ID == Slug(name) {
+			return b, nil
+		}
+	}
+	return nil, unknownBoss(name)
+}
+
 func (s *Service) BossHistory(ctx context.Context, world, name string, limit int) ([]models.SpawnChance, error) {
-	return s.store.GetBossHistory(world, name, limit)
+	world, err := s.canonicalWorld(world)
+	if err != nil {
+		return nil, err
+	}
+	canonical, known := s.resolveBoss(name)
+	list, err := s.store.GetBossHistory(world, canonical, limit)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		if !known {
+			return nil, unknownBoss(name)
+		}
+		list = []models.SpawnChance{}
+	}
+	return list, nil
 }
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"tibia-nemesis-api/internal/config"
+	"tibia-nemesis-api/internal/models"
+	"tibia-nemesis-api/internal/store"
+)
+
+func intp(v int) *int { return &v }
+
+func TestBossesOfWorldWithoutData(t *testing.T) {
+	svc, _, _ := newMetadataService(t, metadataV1)
+	resp, err := svc.Bosses(context.Background(), "secura")
+	if err != nil {
+		t.Fatalf("known world without data: %v", err)
+	}
+	if resp.World != "Secura" || len(resp.Bosses) != 3 {
+		t.Errorf("got %s with %d bosses, want Secura with the 3 metadata bosses", resp.World, len(resp.Bosses))
+	}
+	for _, b := range resp.Bosses {
+		if b.Percent != nil || b.DaysSinceKill != nil || !b.Spawnable {
+			t.Errorf("%s: %+v, want spawnable without chances", b.Name, b)
+		}
+	}
+	if _, err := svc.Bosses(context.Background(), "Nowhere"); err == nil {
+		t.Error("unknown world returned bosses")
+	}
+}
+
+func TestSpawnableWatchFiresOnTransition(t *testing.T) {
+	svc, st, _ := newMetadataService(t, metadataV1)
+	ctx := context.Background()
+
+	var e *Error
+	err := svc.CreateWatch(ctx, &models.Watch{Subscriber: "alice", World: "Antica", Boss: "Nobody", Condition: models.ConditionSpawnable})
+	if !errors.As(err, &e) || e.Code != CodeUnknownBoss {
+		t.Fatalf("watch on an unknown boss returned %v, want unknown_boss", err)
+	}
+	w := &models.Watch{Subscriber: "alice", World: "Antica", Boss: "furyosa", Condition: models.ConditionSpawnable}
+	if err := svc.CreateWatch(ctx, w); err != nil {
+		t.Fatal(err)
+	}
+	if w.Boss != "Furyosa" {
+		t.Errorf("watch stored for %q, want the canonical Furyosa", w.Boss)
+	}
+
+	// Furyosa is spawnable from 12 days since her last kill
+	start := time.Now().UTC().AddDate(0, 0, -3)
+	alerts := func(day, days int) int {
+		t.Helper()
+		scrape := []models.SpawnChance{{Name: "Furyosa", DaysSinceKill: &days, UpdatedAt: start.AddDate(0, 0, day)}}
+		if err := st.UpsertSpawnChances("Antica", scrape); err != nil {
+			t.Fatal(err)
+		}
+		svc.evaluateWatches(ctx, "Antica")
+		list, err := st.ListAlerts(store.AlertFilter{Subscriber: "alice"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Put the alerts in an earlier cycle, so only the condition keeps the
+		// watch from firing again
+		if _, err := st.DB.Exec(`UPDATE alerts SET cycle='earlier-' || id`); err != nil {
+			t.Fatal(err)
+		}
+		return len(list)
+	}
+	if n := alerts(0, 10); n != 0 {
+		t.Errorf("fired %d times before Furyosa was spawnable", n)
+	}
+	if n := alerts(1, 12); n != 1 {
+		t.Errorf("fired %d times when Furyosa became spawnable, want once", n)
+	}
+	if n := alerts(2, 13); n != 1 {
+		t.Errorf("fired again while Furyosa stayed spawnable (%d alerts)", n)
+	}
+	if n := alerts(3, 0); n != 1 {
+		t.Errorf("fired when Furyosa was killed (%d alerts)", n)
+	}
+}
+
+func TestNewRejectsInvalidSchedule(t *testing.T) {
+	_, st, cfg := newMetadataService(t, metadataV1)
+	for _, change := range []func(*config.Config){
+		func(c *config.Config) { c.TZ = "Mars/Olympus" },
+		func(c *config.Config) { c.RefreshAt = "25:00" },
+	} {
+		bad := cfg
+		change(&bad)
+		if _, err := New(st, nil, bad); err == nil {
+			t.Errorf("started with tz %q and refresh_at %q", bad.TZ, bad.RefreshAt)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"tibia-nemesis-api/internal/models"
+	"tibia-nemesis-api/internal/store"
+)
+
+func (s *Service) CreateWatch(ctx context.Context, w *models.Watch) error {
+	w.Subscriber = strings.TrimSpace(w.Subscriber)
+	w.World = strings.TrimSpace(w.World)
+	w.Boss = strings.TrimSpace(w.Boss)
+	if w.Subscriber == "" || w.World == "" || w.Boss == "" {
+		return validationError("subscriber, world and boss are required")
+	}
+	world, err := s.canonicalWorld(w.World)
+	if err != nil {
+		return err
+	}
+	w.World = world
+	switch w.Condition {
+	case models.ConditionSpawnable:
+		w.Value = nil
+	case models.ConditionPercentGTE:
+		if w.Value == nil || *w.Value < 0 || *w.Value > 100 {
+			return validationError("%s requires a value between 0 and 100", w.Condition)
+		}
+	case models.ConditionDaysGTE:
+		if w.Value == nil || *w.Value < 0 {
+			return validationError("%s requires a non-negative value", w.Condition)
+		}
+	default:
+		return validationError("unknown condition %q", w.Condition)
+	}
+	// Store the canonical name of a boss known by name, ID or alias
+	boss, ok := s.resolveBoss(w.Boss)
+	if !ok {
+		return unknownBoss(w.Boss)
+	}
+	w.Boss = boss
+	return s.store.CreateWatch(w)
+}
+
+func (s *Service) Watches(ctx context.Context, subscriber string) ([]models.Watch, error) {
+	return s.store.ListWatches(subscriber)
+}
+
+func (s *Service) DeleteWatch(ctx context.Context, subscriber string, id int64) error {
+	return notFound(s.store.DeleteWatch(subscriber, id), "watch")
+}
+
+func (s *Service) Alerts(ctx context.Context, f store.AlertFilter) ([]models.Alert, error) {
+	return s.store.ListAlerts(f)
+}
+
+// evaluateWatches fires every watch on world whose condition holds after a refresh.
+// Alerts are deduplicated per watch and cycle (the refresh day in the scheduler's time zone).
+// Spawnable watches only fire when the boss becomes spawnable, i.e. when the condition
+// did not hold at the previous evaluation.
+func (s *Service) evaluateWatches(ctx context.Context, world string) {
+	watches, err := s.store.WatchesForWorld(world)
+	if err != nil {
+		slog.ErrorContext(ctx, "watches: load failed", "world", world, "err", err)
+		return
+	}
+	if len(watches) == 0 {
+		return
+	}
+	resp, err := s.Bosses(ctx, world)
+	if err != nil {
+		slog.ErrorContext(ctx, "watches: load bosses failed", "world", world, "err", err)
+		return
+	}
+	bosses := make(map[string]models.BossInfo, len(resp.Bosses))
+	for _, b := range resp.Bosses {
+		bosses[strings.ToLower(b.Name)] = b
+	}
+
+	now := time.Now()
+	cycle := now.In(s.location()).Format("2006-01-02")
+	fired := 0
+	for _, w := range watches {
+		b, ok := bosses[strings.ToLower(w.Boss)]
+		if !ok {
+			continue
+		}
+		held := watchHolds(w, b)
+		if held != w.Held {
+			if err := s.store.SetWatchHeld(w.ID, held); err != nil {
+				slog.ErrorContext(ctx, "watches: record state failed", "world", world, "watch_id", w.ID, "err", err)
+				continue
+			}
+		}
+		if !held || (w.Condition == models.ConditionSpawnable && w.Held) {
+			continue
+		}
+		alert := models.Alert{
+			WatchID:       w.ID,
+			Subscriber:    w.Subscriber,
+			World:         w.World,
+			Boss:          w.Boss,
+			Condition:     w.Condition,
+			Value:         w.Value,
+			Percent:       b.Percent,
+			DaysSinceKill: b.DaysSinceKill,
+			Cycle:         cycle,
+			TriggeredAt:   now.UTC(),
+		}
+		inserted, err := s.store.InsertAlert(&alert)
+		if err != nil {
+			slog.ErrorContext(ctx, "watches: record alert failed", "world", world, "watch_id", w.ID, "err", err)
+			continue
+		}
+		if !inserted {
+			continue
+		}
+		fired++
+		s.webhooks.Emit(models.EventWatchTriggered, world, w.Boss, alert)
+	}
+	if fired > 0 {
+		slog.InfoContext(ctx, "watches: alerts triggered", "world", world, "alerts", fired)
+	}
+}
+
+func watchHolds(w models.Watch, b models.BossInfo) bool {
+	switch w.Condition {
+	case models.ConditionSpawnable:
+		return b.Spawnable
+	case models.ConditionPercentGTE:
+		return b.Percent != nil && w.Value != nil && *b.Percent >= *w.Value
+	case models.ConditionDaysGTE:
+		return b.DaysSinceKill != nil && w.Value != nil && *b.DaysSinceKill >= *w.Value
+	}
+	return false
+}
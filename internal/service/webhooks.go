@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"tibia-nemesis-api/internal/models"
+	"tibia-nemesis-api/internal/webhook"
+)
+
+var webhookEvents = map[string]bool{
+	models.EventRefreshCompleted: true,
+	models.EventBossThreshold:    true,
+	models.EventWatchTriggered:   true,
+}
+
+// StartWebhooks runs the webhook delivery loop
+func (s *Service) StartWebhooks() {
+	s.webhooks.Run()
+}
+
+func (s *Service) CreateWebhook(ctx context.Context, wh *models.Webhook) error {
+	if err := s.validateWebhook(wh); err != nil {
+		return err
+	}
+	if wh.Secret == "" {
+		wh.Secret = webhook.NewSecret()
+	}
+	return s.store.CreateWebhook(wh)
+}
+
+func (s *Service) UpdateWebhook(ctx context.Context, wh *models.Webhook) error {
+	if err := s.validateWebhook(wh); err != nil {
+		return err
+	}
+	return notFound(s.store.UpdateWebhook(wh), "webhook")
+}
+
+func (s *Service) DeleteWebhook(ctx context.Context, id int64) error {
+	return notFound(s.store.DeleteWebhook(id), "webhook")
+}
+
+func (s *Service) Webhook(ctx context.Context, id int64) (*models.Webhook, error) {
+	wh, err := s.store.GetWebhook(id)
+	if err != nil {
+		return nil, notFound(err, "webhook")
+	}
+	wh.Secret = ""
+	return wh, nil
+}
+
+func (s *Service) Webhooks(ctx context.Context) ([]models.Webhook, error) {
+	list, err := s.store.ListWebhooks(false)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list {
+		list[i].Secret = ""
+	}
+	return list, nil
+}
+
+// WebhookDelivery is a delivery together with its attempt log
+type WebhookDelivery struct {
+	models.WebhookDelivery
+	AttemptLog []models.DeliveryAttempt `json:"attempt_log"`
+}
+
+func (s *Service) WebhookDeliveries(ctx context.Context, id int64, limit int) ([]WebhookDelivery, error) {
+	if _, err := s.store.GetWebhook(id); err != nil {
+		return nil, notFound(err, "webhook")
+	}
+	list, err := s.store.ListDeliveries(id, limit)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]WebhookDelivery, 0, len(list))
+	for _, d := range list {
+		attempts, err := s.store.ListDeliveryAttempts(d.ID)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, WebhookDelivery{WebhookDelivery: d, AttemptLog: attempts})
+	}
+	return out, nil
+}
+
+// TestWebhook queues a ping delivery so receivers can verify signatures end to end
+func (s *Service) TestWebhook(ctx context.Context, id int64) (*models.WebhookDelivery, error) {
+	wh, err := s.store.GetWebhook(id)
+	if err != nil {
+		return nil, notFound(err, "webhook")
+	}
+	if !wh.Active {
+		return nil, validationError("webhook is inactive")
+	}
+	return s.webhooks.Enqueue(*wh, models.EventPing, map[string]any{"webhook_id": wh.ID})
+}
+
+// notifyRefresh emits refresh.completed and any boss.threshold crossings for a refreshed world
+func (s *Service) notifyRefresh(ctx context.Context, world string, prev, next []models.SpawnChance) {
+	s.webhooks.Emit(models.EventRefreshCompleted, world, "", map[string]any{
+		"world":      world,
+		"bosses":     len(next),
+		"updated_at": time.Now().UTC(),
+	})
+
+	hooks, err := s.store.ListWebhooks(true)
+	if err != nil {
+		slog.ErrorContext(ctx, "webhook: list webhooks", "err", err)
+		return
+	}
+	prevPercent := make(map[string]*int, len(prev))
+	for _, c := range prev {
+		prevPercent[strings.ToLower(c.Name)] = c.Percent
+	}
+	for _, wh := range hooks {
+		if wh.ThresholdPercent == nil {
+			continue
+		}
+		threshold := *wh.ThresholdPercent
+		for _, c := range next {
+			if c.Percent == nil || *c.Percent < threshold {
+				continue
+			}
+			before := prevPercent[strings.ToLower(c.Name)]
+			if before != nil && *before >= threshold {
+				continue
+			}
+			if !webhook.Matches(wh, models.EventBossThreshold, world, c.Name) {
+				continue
+			}
+			if _, err := s.webhooks.Enqueue(wh, models.EventBossThreshold, map[string]any{
+				"world":            world,
+				"boss":             c.Name,
+				"percent":          c.Percent,
+				"previous_percent": before,
+				"threshold":        threshold,
+				"days_since_kill":  c.DaysSinceKill,
+			}); err != nil {
+				slog.ErrorContext(ctx, "webhook: enqueue failed", "event", models.EventBossThreshold, "webhook_id", wh.ID, "err", err)
+			}
+		}
+	}
+}
+
+func (s *Service) validateWebhook(wh *models.Webhook) error {
+	if wh.World != "" {
+		world, err := s.canonicalWorld(wh.World)
+		if err != nil {
+			return err
+		}
+		wh.World = world
+	}
+	for i, b := range wh.Bosses {
+		wh.Bosses[i], _ = s.resolveBoss(b)
+	}
+	u, err := url.Parse(wh.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return validationError("url must be an absolute http(s) URL")
+	}
+	if len(wh.Events) == 0 {
+		return validationError("at least one event is required")
+	}
+	for _, e := range wh.Events {
+		if !webhookEvents[e] {
+			return validationError("unknown event %q", e)
+		}
+		if e == models.EventBossThreshold && wh.ThresholdPercent == nil {
+			return validationError("threshold_percent is required for %s", e)
+		}
+	}
+	if wh.ThresholdPercent != nil && (*wh.ThresholdPercent < 0 || *wh.ThresholdPercent > 100) {
+		return validationError("threshold_percent must be between 0 and 100")
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// defaultWorlds are the Tibia game worlds known at build time. They only seed
+// the registry: worlds with stored data are added at startup, and worlds the
+// data source knows are added when they are first refreshed. Set WORLDS to
+// replace the list.
+var defaultWorlds = []string{
+	"Aethera", "Antica", "Astera", "Axera", "Bastia", "Batabra", "Belobra", "Blumera", "Bombra", "Bona",
+	"Cadebra", "Calmera", "Castela", "Celebra", "Celesta", "Collabra", "Damora", "Descubra", "Dia", "Dracobra",
+	"Eclipta", "Epoca", "Esmera", "Etebra", "Ferobra", "Firmera", "Flamera", "Gentebra", "Gladera", "Gravitera",
+	"Guerribra", "Harmonia", "Havera", "Honbra", "Idyllia", "Ignitera", "Inabra", "Issobra", "Jacabra", "Jadebra",
+	"Jaguna", "Kalibra", "Kardera", "Kendria", "Lobera", "Luminera", "Lutabra", "Menera", "Monza", "Mykera",
+	"Nadora", "Nefera", "Nevia", "Noctalia", "Obscubra", "Oceanis", "Ombra", "Ousabra", "Pacera", "Peloria",
+	"Premia", "Pulsera", "Quelibra", "Quintera", "Rasteibra", "Refugia", "Retalia", "Runera", "Secura", "Serdebra",
+	"Solidera", "Sonira", "Stralis", "Talera", "Thyria", "Tornabra", "Unebra", "Ustebra", "Utobra", "Vandera",
+	"Venebra", "Victoris", "Vitera", "Wadira", "Wildera", "Wintera", "Xandebra", "Xybra", "Xyla", "Yara",
+	"Yonabra", "Yovera", "Yubra", "Zephyra", "Zuna", "Zunera",
+}
+
+// WorldRegistry resolves user supplied world names to their canonical casing
+type WorldRegistry struct {
+	mu    sync.RWMutex
+	byKey map[string]string
+}
+
+// NewWorldRegistry builds a registry from names, falling back to the built-in list when empty
+func NewWorldRegistry(names []string) *WorldRegistry {
+	if len(names) == 0 {
+		names = defaultWorlds
+	}
+	r := &WorldRegistry{byKey: make(map[string]string, len(names))}
+	r.Add(names...)
+	return r
+}
+
+// Add registers worlds; registered ones keep their casing
+func (r *WorldRegistry) Add(names ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, n := range names {
+		n = strings.TrimSpace(n)
+		if n == "" {
+			continue
+		}
+		if _, ok := r.byKey[strings.ToLower(n)]; !ok {
+			r.byKey[strings.ToLower(n)] = n
+		}
+	}
+}
+
+// Canonical returns the registered casing of world
+func (r *WorldRegistry) Canonical(world string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	name, ok := r.byKey[strings.ToLower(strings.TrimSpace(world))]
+	return name, ok
+}
+
+// All returns every registered world, sorted
+func (r *WorldRegistry) All() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]string, 0, len(r.byKey))
+	for _, n := range r.byKey {
+		out = append(out, n)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// canonicalWorld validates world against the registry and returns its canonical name
+func (s *Service) canonicalWorld(world string) (string, error) {
+	if strings.TrimSpace(world) == "" {
+		return "", validationError("world required")
+	}
+	name, ok := s.worlds.Canonical(world)
+	if !ok {
+		return "", unknownWorld(world)
+	}
+	return name, nil
+}
+
+// newWorldName returns the canonical casing of a world that is not registered
+// yet, or false when world cannot be a world name. Tibia world names are a
+// single capitalized word.
+func newWorldName(world string) (string, bool) {
+	world = strings.TrimSpace(world)
+	if world == "" || len(world) > 32 {
+		return "", false
+	}
+	for _, c := range world {
+		if c > unicode.MaxASCII || !unicode.IsLetter(c) {
+			return "", false
+		}
+	}
+	return strings.ToUpper(world[:1]) + strings.ToLower(world[1:]), true
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"tibia-nemesis-api/internal/config"
+	"tibia-nemesis-api/internal/models"
+	"tibia-nemesis-api/internal/scraper"
+	"tibia-nemesis-api/internal/store"
+)
+
+// sourceScraper serves the worlds the data source has; others answer 404
+type sourceScraper map[string][]models.SpawnChance
+
+func (s sourceScraper) Fetch(ctx context.Context, world string) ([]models.SpawnChance, error) {
+	list, ok := s[world]
+	if !ok {
+		return nil, &scraper.StatusError{StatusCode: 404, URL: "https://example.com/" + world}
+	}
+	return list, nil
+}
+
+func TestWorldsBeyondTheConfiguredList(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.Defaults()
+	cfg.MetadataPath = filepath.Join(dir, "missing.yaml")
+	cfg.Worlds = []string{"Antica"}
+	st, err := store.NewSQLite(filepath.Join(dir, "worlds.db"), store.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	// Stored before the world list lost it
+	err = st.UpsertSpawnChances("Retalia", []models.SpawnChance{{Name: "Furyosa", DaysSinceKill: intp(3), UpdatedAt: time.Now().UTC()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	boss := []models.SpawnChance{{Name: "Furyosa", DaysSinceKill: intp(4)}}
+	svc, err := New(st, sourceScraper{"Antica": boss, "Retalia": boss, "Newworld": boss}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if err := svc.RefreshAll(ctx); err != nil {
+		t.Errorf("refreshing the stored worlds: %v", err)
+	}
+	if _, err := svc.Bosses(ctx, "retalia"); err != nil {
+		t.Errorf("stored world: %v", err)
+	}
+
+	// A world launched after the list was written is registered by its first refresh
+	if _, err := svc.Bosses(ctx, "Newworld"); err == nil {
+		t.Error("world that was never refreshed is known")
+	}
+	if err := svc.RefreshWorld(ctx, "newworld"); err != nil {
+		t.Fatalf("refreshing a world the data source has: %v", err)
+	}
+	if resp, err := svc.Bosses(ctx, "NEWWORLD"); err != nil || resp.World != "Newworld" {
+		t.Errorf("new world: %+v %v", resp, err)
+	}
+
+	for _, world := range []string{"Nowhere", "../admin"} {
+		var e *Error
+		if err := svc.RefreshWorld(ctx, world); !errors.As(err, &e) || e.Code != CodeUnknownWorld {
+			t.Errorf("refreshing %q returned %v, want unknown_world", world, err)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Backup writes a consistent copy of the live database to path with
+// VACUUM INTO, and the copy is compacted. It runs on a read connection, which
+// in WAL mode reads a snapshot of the database, so readers and writers are not
+// blocked. path must not exist yet.
+func (s *SQLite) Backup(path string) error {
+	defer observe("backup", time.Now())
+	_, err := s.read.Exec(`VACUUM INTO ?`, path)
+	return err
+}
+
+// SnapshotVersion checks that path is an intact database of this service and
+// returns its schema version (PRAGMA user_version). The file is opened read-only.
+func SnapshotVersion(path string) (int, error) {
+	if _, err := os.Stat(path); err != nil {
+		return 0, err
+	}
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+	var check string
+	if err := db.QueryRow(`PRAGMA quick_check`).Scan(&check); err != nil {
+		return 0, fmt.Errorf("not a readable SQLite database: %w", err)
+	}
+	if check != "ok" {
+		return 0, fmt.Errorf("integrity check failed: %s", check)
+	}
+	var tables int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name IN ('spawn_chances', 'observations')`).Scan(&tables); err != nil {
+		return 0, err
+	}
+	if tables != 2 {
+		return 0, fmt.Errorf("not a tibia-nemesis-api database")
+	}
+	var version int
+	err = db.QueryRow(`PRAGMA user_version`).Scan(&version)
+	return version, err
+}
+
+// Restore replaces the database at dbPath with the snapshot. The snapshot must
+// be intact and not newer than this build's schema; older snapshots are
+// migrated when the database is next opened. The replaced database and its
+// journal files are kept next to it with a ".pre-restore" suffix. It fails
+// while another process, like a running server, has the database open, and a
+// failure leaves the live database in place.
+func Restore(snapshot, dbPath string) (int, error) {
+	version, err := SnapshotVersion(snapshot)
+	if err != nil {
+		return 0, fmt.Errorf("snapshot %s: %w", snapshot, err)
+	}
+	if version > SchemaVersion() {
+		return version, fmt.Errorf("snapshot %s has schema version %d, this build supports up to %d", snapshot, version, SchemaVersion())
+	}
+
+	unlock, err := lockDatabase(dbPath)
+	if err != nil {
+		return version, err
+	}
+	defer unlock()
+
+	// Copy first so a failure leaves the live database untouched
+	tmp := dbPath + ".restore"
+	if err := copyFile(snapshot, tmp); err != nil {
+		os.Remove(tmp)
+		return version, err
+	}
+	var moved []string
+	undo := func() {
+		for i := len(moved) - 1; i >= 0; i-- {
+			os.Rename(dbPath+".pre-restore"+moved[i], dbPath+moved[i])
+		}
+		os.Remove(tmp)
+	}
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		err := os.Rename(dbPath+suffix, dbPath+".pre-restore"+suffix)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			undo()
+			return version, err
+		}
+		moved = append(moved, suffix)
+	}
+	if err := os.Rename(tmp, dbPath); err != nil {
+		undo()
+		return version, err
+	}
+	return version, nil
+}
+
+// lockDatabase takes an exclusive lock on the database at dbPath, which fails
+// while another connection has it open. The lock is held until the returned
+// function is called.
+func lockDatabase(dbPath string) (func(), error) {
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return func() {}, nil
+	}
+	db, err := sql.Open("sqlite", "file:"+dbPath+"?_pragma=locking_mode(EXCLUSIVE)&_pragma=busy_timeout(1000)")
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(`BEGIN EXCLUSIVE`); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("database %s is in use, stop the server first: %w", dbPath, err)
+	}
+	return func() {
+		db.Exec(`ROLLBACK`)
+		db.Close()
+	}, nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
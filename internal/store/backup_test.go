@@ -0,0 +1,106 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"tibia-nemesis-api/internal/models"
+)
+
+// backupFixture stores a scrape of Antica in a new database at dir/live.db
+// and writes a snapshot of it to dir/snapshot.db
+func backupFixture(t *testing.T, dir string) (live, snapshot string) {
+	t.Helper()
+	live = filepath.Join(dir, "live.db")
+	s, err := NewSQLite(live, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	err = s.UpsertSpawnChances("Antica", []models.SpawnChance{{Name: "Furyosa", DaysSinceKill: intp(3), UpdatedAt: time.Now().UTC()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot = filepath.Join(dir, "snapshot.db")
+	if err := s.Backup(snapshot); err != nil {
+		t.Fatal(err)
+	}
+	return live, snapshot
+}
+
+func worldsIn(t *testing.T, path string) []string {
+	t.Helper()
+	s, err := NewSQLite(path, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	worlds, err := s.GetWorlds()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return worlds
+}
+
+func TestRestore(t *testing.T) {
+	dir := t.TempDir()
+	_, snapshot := backupFixture(t, dir)
+	target := filepath.Join(dir, "target.db")
+	s, err := NewSQLite(target, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.UpsertSpawnChances("Secura", []models.SpawnChance{{Name: "Furyosa", DaysSinceKill: intp(5), UpdatedAt: time.Now().UTC()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Refused while the database is open
+	if _, err := Restore(snapshot, target); err == nil || !strings.Contains(err.Error(), "in use") {
+		t.Fatalf("restore into an open database returned %v", err)
+	}
+	s.Close()
+	if got := worldsIn(t, target); len(got) != 1 || got[0] != "Secura" {
+		t.Fatalf("refused restore changed the database: %v", got)
+	}
+
+	version, err := Restore(snapshot, target)
+	if err != nil || version != SchemaVersion() {
+		t.Fatalf("restore: version %d, %v", version, err)
+	}
+	if got := worldsIn(t, target); len(got) != 1 || got[0] != "Antica" {
+		t.Errorf("restored database has %v, want the snapshot's Antica", got)
+	}
+	if got := worldsIn(t, target+".pre-restore"); len(got) != 1 || got[0] != "Secura" {
+		t.Errorf("kept database has %v, want Secura", got)
+	}
+}
+
+func TestRestoreRefusesNewerSchema(t *testing.T) {
+	dir := t.TempDir()
+	live, snapshot := backupFixture(t, dir)
+	db, err := sql.Open("sqlite", "file:"+snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, SchemaVersion()+1)); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	_, err = Restore(snapshot, live)
+	if err == nil || !strings.Contains(err.Error(), "schema version") {
+		t.Fatalf("restoring a newer snapshot returned %v", err)
+	}
+	if _, err := os.Stat(live + ".pre-restore"); !os.IsNotExist(err) {
+		t.Error("refused restore moved the live database")
+	}
+	if got := worldsIn(t, live); len(got) != 1 || got[0] != "Antica" {
+		t.Errorf("refused restore changed the database: %v", got)
+	}
+}
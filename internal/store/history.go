@@ -0,0 +1,317 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"tibia-nemesis-api/internal/models"
+)
+
+// killDateLayout is the format of kills.killed_on
+const killDateLayout = "2006-01-02"
+
+// observationColumns are the columns scanned by scanRun, in order
+const observationColumns = `world, name, percent, days_since_kill, is_no_chance, observed_at, seen_until, seen_offsets`
+
+// observationRun is a stored observation and the scrapes on the following
+// days that only confirmed it: same percent and no chance flag, days since
+// kill counting up by one per day. Only scrapes that deviate from the
+// expected value, or that don't fall on the day after the run's last one,
+// are stored as new rows; the others are appended to the run. The exact time
+// of every confirming scrape is kept, so a run expands back to the scrapes it
+// was recorded from.
+type observationRun struct {
+	ID int64
+	models.SpawnChance
+	SeenUntil time.Time       // Last confirming scrape, zero if there was none
+	Seen      []time.Duration // Confirming scrapes as offsets from UpdatedAt, oldest first
+}
+
+// lastSeen returns the time of the run's latest scrape
+func (r observationRun) lastSeen() time.Time {
+	if len(r.Seen) > 0 {
+		return r.UpdatedAt.Add(r.Seen[len(r.Seen)-1])
+	}
+	return r.UpdatedAt
+}
+
+// sameState reports whether e is what the run predicts for e's date
+func (r observationRun) sameState(e models.SpawnChance) bool {
+	if (r.Percent == nil) != (e.Percent == nil) || (r.Percent != nil && *r.Percent != *e.Percent) {
+		return false
+	}
+	if r.IsNoChance != e.IsNoChance || (r.DaysSinceKill == nil) != (e.DaysSinceKill == nil) {
+		return false
+	}
+	return r.DaysSinceKill == nil || *e.DaysSinceKill == *r.DaysSinceKill+daysBetween(r.UpdatedAt, e.UpdatedAt)
+}
+
+// continues reports whether e can be appended to the run: it is what the run
+// predicts and falls on the day after the run's last scrape. A run so never
+// spans a day that wasn't scraped.
+func (r observationRun) continues(e models.SpawnChance) bool {
+	return daysBetween(r.lastSeen(), e.UpdatedAt) == 1 && r.sameState(e)
+}
+
+// add appends the confirming scrape at t
+func (r *observationRun) add(t time.Time) {
+	r.Seen = append(r.Seen, t.Sub(r.UpdatedAt))
+	r.SeenUntil = t
+}
+
+// expand returns the scrapes of the run, oldest first: the stored row, then
+// every confirming scrape at its own time
+func (r observationRun) expand() []models.SpawnChance {
+	out := []models.SpawnChance{r.SpawnChance}
+	for _, off := range r.Seen {
+		o := r.SpawnChance
+		o.UpdatedAt = r.UpdatedAt.Add(off)
+		if r.DaysSinceKill != nil {
+			d := *r.DaysSinceKill + daysBetween(r.UpdatedAt, o.UpdatedAt)
+			o.DaysSinceKill = &d
+		}
+		out = append(out, o)
+	}
+	return out
+}
+
+// encodeOffsets stores confirming scrapes as comma-separated nanoseconds,
+// NULL for a run without any
+func encodeOffsets(seen []time.Duration) any {
+	if len(seen) == 0 {
+		return nil
+	}
+	parts := make([]string, len(seen))
+	for i, d := range seen {
+		parts[i] = strconv.FormatInt(int64(d), 10)
+	}
+	return strings.Join(parts, ",")
+}
+
+func parseOffsets(s string) ([]time.Duration, error) {
+	if s == "" {
+		return nil, nil
+	}
+	parts := strings.Split(s, ",")
+	out := make([]time.Duration, len(parts))
+	for i, p := range parts {
+		n, err := strconv.ParseInt(p, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad seen_offsets %q", s)
+		}
+		out[i] = time.Duration(n)
+	}
+	return out, nil
+}
+
+// daysBetween counts UTC calendar days from a to b
+func daysBetween(a, b time.Time) int {
+	ay, am, ad := a.UTC().Date()
+	by, bm, bd := b.UTC().Date()
+	return int(time.Date(by, bm, bd, 0, 0, 0, 0, time.UTC).Sub(time.Date(ay, am, ad, 0, 0, 0, 0, time.UTC)).Hours() / 24)
+}
+
+func scanRun(rows *sql.Rows) (observationRun, error) {
+	var r observationRun
+	var percent, days sql.NullInt64
+	var isNoChance int
+	var seen sql.NullTime
+	var offsets sql.NullString
+	if err := rows.Scan(&r.ID, &r.World, &r.Name, &percent, &days, &isNoChance, &r.UpdatedAt, &seen, &offsets); err != nil {
+		return r, err
+	}
+	r.Percent, r.DaysSinceKill, r.IsNoChance = intPtr(percent), intPtr(days), isNoChance == 1
+	r.SeenUntil = seen.Time
+	var err error
+	r.Seen, err = parseOffsets(offsets.String)
+	return r, err
+}
+
+// observationRecorder adds scraped rows to the observation history within a
+// transaction, with its statements prepared once for all rows
+type observationRecorder struct {
+	last, insert, extend, knownKill, insertKill *sql.Stmt
+}
+
+func newObservationRecorder(tx *sql.Tx) (*observationRecorder, error) {
+	rec := &observationRecorder{}
+	for _, p := range []struct {
+		stmt **sql.Stmt
+		q    string
+	}{
+		{&rec.last, `SELECT id, ` + observationColumns + ` FROM observations WHERE world=? AND name=? ORDER BY observed_at DESC, id DESC LIMIT 1`},
+		{&rec.insert, `INSERT INTO observations (world, name, percent, days_since_kill, is_no_chance, observed_at) VALUES (?, ?, ?, ?, ?, ?)`},
+		{&rec.extend, `UPDATE observations SET seen_until=?, seen_offsets=? WHERE id=?`},
+		{&rec.knownKill, `SELECT COUNT(*) FROM kills WHERE world=? AND name=? AND killed_on BETWEEN ? AND ?`},
+		{&rec.insertKill, `INSERT OR IGNORE INTO kills (world, name, killed_on, observed_at) VALUES (?, ?, ?, ?)`},
+	} {
+		stmt, err := tx.Prepare(p.q)
+		if err != nil {
+			rec.Close()
+			return nil, err
+		}
+		*p.stmt = stmt
+	}
+	return rec, nil
+}
+
+func (rec *observationRecorder) Close() {
+	for _, stmt := range []*sql.Stmt{rec.last, rec.insert, rec.extend, rec.knownKill, rec.insertKill} {
+		if stmt != nil {
+			stmt.Close()
+		}
+	}
+}
+
+// record adds a scraped row, extending the boss's latest run when the row is
+// what it predicts for the following day, and records the kill it implies. Consecutive scrapes of
+// one kill can land a day apart (the scrape time drifts around midnight UTC),
+// so a kill within a day of a known kill of the same boss is the same kill.
+func (rec *observationRecorder) record(world string, e models.SpawnChance) error {
+	observed := e.UpdatedAt.UTC()
+	e.UpdatedAt = observed
+	rows, err := rec.last.Query(world, e.Name)
+	if err != nil {
+		return err
+	}
+	var last *observationRun
+	if rows.Next() {
+		r, err := scanRun(rows)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		last = &r
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if last != nil && last.continues(e) {
+		last.add(observed)
+		_, err = rec.extend.Exec(observed, encodeOffsets(last.Seen), last.ID)
+	} else {
+		_, err = rec.insert.Exec(world, e.Name, nullInt(e.Percent), nullInt(e.DaysSinceKill), boolInt(e.IsNoChance), observed)
+	}
+	if err != nil {
+		return err
+	}
+
+	if e.DaysSinceKill == nil || *e.DaysSinceKill < 0 {
+		return nil
+	}
+	killed := observed.AddDate(0, 0, -*e.DaysSinceKill)
+	var known int
+	err = rec.knownKill.QueryRow(world, e.Name, killed.AddDate(0, 0, -1).Format(killDateLayout), killed.AddDate(0, 0, 1).Format(killDateLayout)).Scan(&known)
+	if err != nil || known > 0 {
+		return err
+	}
+	_, err = rec.insertKill.Exec(world, e.Name, killed.Format(killDateLayout), observed)
+	return err
+}
+
+// GetBossHistory returns the newest observations of a boss first, with the
+// scrapes of each stored run reconstructed
+func (s *SQLite) GetBossHistory(world, name string, limit int) ([]models.SpawnChance, error) {
+	defer observe("get_boss_history", time.Now())
+	if limit <= 0 {
+		limit = 25
+	}
+	// Every run expands to at least one observation, so limit runs are enough
+	rows, err := s.bossHistory.Query(world, name, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []models.SpawnChance
+	for rows.Next() && len(out) < limit {
+		r, err := scanRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		series := r.expand()
+		for i := len(series) - 1; i >= 0 && len(out) < limit; i-- {
+			out = append(out, series[i])
+		}
+	}
+	return out, rows.Err()
+}
+
+// GetKills returns the newest kills of a boss on world first
+func (s *SQLite) GetKills(world, name string, limit int) ([]models.Kill, error) {
+	defer observe("get_kills", time.Now())
+	rows, err := s.kills.Query(world, name, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanKills(rows)
+}
+
+// AllKills returns every kill ordered by boss, world and date
+func (s *SQLite) AllKills() ([]models.Kill, error) {
+	defer observe("all_kills", time.Now())
+	rows, err := s.read.Query(`SELECT world, name, killed_on, observed_at FROM kills ORDER BY name, world, killed_on`)
+	if err != nil {
+		return nil, err
+	}
+	return scanKills(rows)
+}
+
+func scanKills(rows *sql.Rows) ([]models.Kill, error) {
+	defer rows.Close()
+	var out []models.Kill
+	for rows.Next() {
+		var k models.Kill
+		if err := rows.Scan(&k.World, &k.Name, &k.KilledOn, &k.ObservedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, k)
+	}
+	return out, rows.Err()
+}
+
+// EachObservation calls fn for every observation, optionally of one world and
+// observed in [from, to) when those are not zero, ordered by world, boss and
+// time. Runs are expanded to the scrapes they were recorded from. Rows are streamed, not
+// loaded at once.
+func (s *SQLite) EachObservation(world string, from, to time.Time, fn func(models.SpawnChance) error) error {
+	defer observe("each_observation", time.Now())
+	q := `SELECT id, ` + observationColumns + ` FROM observations WHERE 1=1`
+	var args []any
+	if world != "" {
+		q += ` AND world=?`
+		args = append(args, world)
+	}
+	if !from.IsZero() {
+		q += ` AND COALESCE(seen_until, observed_at) >= ?`
+		args = append(args, from.UTC())
+	}
+	if !to.IsZero() {
+		q += ` AND observed_at < ?`
+		args = append(args, to.UTC())
+	}
+	rows, err := s.read.Query(q+` ORDER BY world, name, observed_at, id`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		r, err := scanRun(rows)
+		if err != nil {
+			return err
+		}
+		for _, o := range r.expand() {
+			if (!from.IsZero() && o.UpdatedAt.Before(from)) || (!to.IsZero() && !o.UpdatedAt.Before(to)) {
+				continue
+			}
+			if err := fn(o); err != nil {
+				return err
+			}
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,282 @@
+package store
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"tibia-nemesis-api/internal/models"
+)
+
+func openTestStore(t testing.TB) *SQLite {
+	t.Helper()
+	s, err := NewSQLite(filepath.Join(t.TempDir(), "test.db"), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func intp(v int) *int { return &v }
+
+// scrapeSeries returns what daily scrapes of three bosses could look like over
+// two months: scrape times that drift by the second and the nanosecond, missed
+// days, a day scraped twice, kills and a percent that changes.
+func scrapeSeries(start time.Time) [][]models.SpawnChance {
+	var scrapes [][]models.SpawnChance
+	days := 10 // Days since the first boss was killed
+	percent := 20
+	for day := 0; day < 60; day++ {
+		if day%11 == 5 || day == 30 || day == 31 {
+			// Missed days; the bosses' days since kill keep counting
+			days++
+			continue
+		}
+		at := start.AddDate(0, 0, day).Add(time.Duration(day*37)*time.Second + time.Duration(day*1013))
+		if day%17 == 0 {
+			percent += 5
+		}
+		if day == 24 || day == 45 {
+			days = 0
+		}
+		batch := []models.SpawnChance{
+			{Name: "Furyosa", Percent: intp(percent), DaysSinceKill: intp(days), UpdatedAt: at},
+			{Name: "Ferumbras", DaysSinceKill: intp(days + 100), UpdatedAt: at},
+			{Name: "Morshabaal", IsNoChance: true, UpdatedAt: at},
+		}
+		scrapes = append(scrapes, batch)
+		if day == 12 || day == 40 {
+			// Scraped again later the same day
+			again := make([]models.SpawnChance, len(batch))
+			copy(again, batch)
+			for i := range again {
+				again[i].UpdatedAt = at.Add(7 * time.Hour)
+			}
+			scrapes = append(scrapes, again)
+		}
+		days++
+	}
+	return scrapes
+}
+
+// byBoss groups scraped rows by boss, oldest first, as history reads them
+func byBoss(world string, scrapes [][]models.SpawnChance) map[string][]models.SpawnChance {
+	out := make(map[string][]models.SpawnChance)
+	for _, batch := range scrapes {
+		for _, e := range batch {
+			e.World = world
+			e.UpdatedAt = e.UpdatedAt.UTC()
+			out[e.Name] = append(out[e.Name], e)
+		}
+	}
+	return out
+}
+
+func describe(o models.SpawnChance) string {
+	num := func(v *int) string {
+		if v == nil {
+			return "-"
+		}
+		return fmt.Sprint(*v)
+	}
+	return fmt.Sprintf("%s/%s %s percent=%s days=%s nochance=%t",
+		o.World, o.Name, o.UpdatedAt.UTC().Format(time.RFC3339Nano), num(o.Percent), num(o.DaysSinceKill), o.IsNoChance)
+}
+
+func compareSeries(t *testing.T, what string, got, want []models.SpawnChance) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("%s: got %d observations, want %d", what, len(got), len(want))
+	}
+	for i := 0; i < len(got) && i < len(want); i++ {
+		if g, w := describe(got[i]), describe(want[i]); g != w || !got[i].UpdatedAt.Equal(want[i].UpdatedAt) {
+			t.Errorf("%s[%d]: got %s, want %s", what, i, g, w)
+			return
+		}
+	}
+}
+
+func readBack(t *testing.T, s *SQLite, world string) map[string][]models.SpawnChance {
+	t.Helper()
+	out := make(map[string][]models.SpawnChance)
+	err := s.EachObservation(world, time.Time{}, time.Time{}, func(o models.SpawnChance) error {
+		out[o.Name] = append(out[o.Name], o)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestObservationRunsRoundTrip(t *testing.T) {
+	s := openTestStore(t)
+	start := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	scrapes := scrapeSeries(start)
+	for _, batch := range scrapes {
+		if err := s.UpsertSpawnChances("Antica", batch); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := byBoss("Antica", scrapes)
+
+	rows, err := s.CountObservations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total := 3 * len(scrapes); rows >= int64(total) {
+		t.Errorf("stored %d rows for %d scrapes, want runs to save rows", rows, total)
+	}
+
+	got := readBack(t, s, "Antica")
+	for name, series := range want {
+		compareSeries(t, name, got[name], series)
+
+		history, err := s.GetBossHistory("Antica", name, len(series))
+		if err != nil {
+			t.Fatal(err)
+		}
+		reversed := make([]models.SpawnChance, len(history))
+		for i, o := range history {
+			reversed[len(history)-1-i] = o
+		}
+		compareSeries(t, name+" history", reversed, series)
+	}
+
+	// Compacting changes how the history is stored, not what it reads back as
+	if _, err := s.DownsampleObservations(start.AddDate(0, 1, 0), DownsampleChanges); err != nil {
+		t.Fatal(err)
+	}
+	got = readBack(t, s, "Antica")
+	for name, series := range want {
+		compareSeries(t, name+" after compacting", got[name], series)
+	}
+}
+
+func TestExpandLegacyRows(t *testing.T) {
+	// Rows stored one per scrape, as before runs existed, are folded into runs
+	s := openTestStore(t)
+	start := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	var want []models.SpawnChance
+	for day := 0; day < 10; day++ {
+		if day == 4 {
+			continue
+		}
+		o := models.SpawnChance{World: "Antica", Name: "Furyosa", Percent: intp(5), DaysSinceKill: intp(day), UpdatedAt: start.AddDate(0, 0, day).Add(time.Duration(day) * time.Minute)}
+		_, err := s.DB.Exec(`INSERT INTO observations (world, name, percent, days_since_kill, is_no_chance, observed_at) VALUES (?, ?, ?, ?, 0, ?)`,
+			o.World, o.Name, *o.Percent, *o.DaysSinceKill, o.UpdatedAt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, o)
+	}
+
+	n, err := s.DownsampleObservations(start.AddDate(0, 1, 0), DownsampleChanges)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 7 {
+		t.Errorf("removed %d rows, want 7 (9 scrapes in 2 runs)", n)
+	}
+	compareSeries(t, "Furyosa", readBack(t, s, "Antica")["Furyosa"], want)
+	if n, _ := s.DownsampleObservations(start.AddDate(0, 1, 0), DownsampleChanges); n != 0 {
+		t.Errorf("compacting again removed %d rows", n)
+	}
+}
+
+func TestDownsampleDaily(t *testing.T) {
+	s := openTestStore(t)
+	start := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	scrapes := scrapeSeries(start)
+	for _, batch := range scrapes {
+		if err := s.UpsertSpawnChances("Antica", batch); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cutoff := start.AddDate(0, 0, 35)
+	want := byBoss("Antica", scrapes)
+	for name, series := range want {
+		// Only the same-day scrape before the cutoff goes; missed days stay missing
+		var kept []models.SpawnChance
+		for i, o := range series {
+			if o.UpdatedAt.Before(cutoff) && i+1 < len(series) && daysBetween(o.UpdatedAt, series[i+1].UpdatedAt) == 0 {
+				continue
+			}
+			kept = append(kept, o)
+		}
+		if len(kept) != len(series)-1 {
+			t.Fatalf("%s: test series has %d same-day scrapes before the cutoff, want 1", name, len(series)-len(kept))
+		}
+		want[name] = kept
+	}
+
+	if _, err := s.DownsampleObservations(cutoff, DownsampleDaily); err != nil {
+		t.Fatal(err)
+	}
+	got := readBack(t, s, "Antica")
+	for name, series := range want {
+		compareSeries(t, name, got[name], series)
+	}
+	if n, err := s.DownsampleObservations(cutoff, DownsampleDaily); err != nil || n != 0 {
+		t.Errorf("downsampling again removed %d rows (%v)", n, err)
+	}
+	got = readBack(t, s, "Antica")
+	for name, series := range want {
+		compareSeries(t, name+" after downsampling again", got[name], series)
+	}
+}
+
+// TestDownsampleDuringRefreshes downsamples a run that started before the
+// cutoff while refreshes keep extending it. Every refresh must survive.
+func TestDownsampleDuringRefreshes(t *testing.T) {
+	s := openTestStore(t)
+	start := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	scrape := func(day int) []models.SpawnChance {
+		return []models.SpawnChance{{Name: "Morshabaal", IsNoChance: true, UpdatedAt: start.AddDate(0, 0, day)}}
+	}
+	if err := s.UpsertSpawnChances("Antica", scrape(0)); err != nil {
+		t.Fatal(err)
+	}
+	cutoff := start.AddDate(0, 0, 1)
+	const days = 150
+
+	done := make(chan error)
+	go func() {
+		for day := 1; day < days; day++ {
+			if err := s.UpsertSpawnChances("Antica", scrape(day)); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+	for refreshing := true; refreshing; {
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatal(err)
+			}
+			refreshing = false
+		default:
+		}
+		// An earlier scrape the same day as the run's first one, so every
+		// pass rewrites the run
+		_, err := s.DB.Exec(`INSERT INTO observations (world, name, is_no_chance, observed_at) VALUES ('Antica', 'Morshabaal', 1, ?)`, start.Add(-time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.DownsampleObservations(cutoff, DownsampleDaily); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var want []models.SpawnChance
+	for day := 0; day < days; day++ {
+		o := scrape(day)[0]
+		o.World = "Antica"
+		want = append(want, o)
+	}
+	compareSeries(t, "Morshabaal", readBack(t, s, "Antica")["Morshabaal"], want)
+}
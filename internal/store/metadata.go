@@ -0,0 +1,173 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"tibia-nemesis-api/internal/models"
+)
+
+// MetadataChange describes one boss metadata edit. Before is nil for a create,
+// After is nil for a delete.
+type MetadataChange struct {
+	Before *models.BossMetadata
+	After  *models.BossMetadata
+}
+
+// Action returns the audit action recorded for the change
+func (c MetadataChange) Action() string {
+	switch {
+	case c.Before == nil:
+		return "create"
+	case c.After == nil:
+		return "delete"
+	}
+	return "update"
+}
+
+// ListBossMetadata returns all stored boss metadata keyed by name
+func (s *SQLite) ListBossMetadata() (map[string]models.BossMetadata, error) {
+	defer observe("list_boss_metadata", time.Now())
+	rows, err := s.read.Query(`SELECT name, doc FROM boss_metadata`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make(map[string]models.BossMetadata)
+	for rows.Next() {
+		var name, doc string
+		if err := rows.Scan(&name, &doc); err != nil {
+			return nil, err
+		}
+		var meta models.BossMetadata
+		if err := json.Unmarshal([]byte(doc), &meta); err != nil {
+			return nil, err
+		}
+		out[name] = meta
+	}
+	return out, rows.Err()
+}
+
+// ApplyMetadataChanges writes changes and their audit entries in one transaction
+func (s *SQLite) ApplyMetadataChanges(actor string, changes []MetadataChange) error {
+	defer observe("apply_metadata_changes", time.Now())
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	for _, c := range changes {
+		if err := applyMetadataChange(tx, actor, c, now); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func applyMetadataChange(tx *sql.Tx, actor string, c MetadataChange, now time.Time) error {
+	before, err := encodeDoc(c.Before)
+	if err != nil {
+		return err
+	}
+	after, err := encodeDoc(c.After)
+	if err != nil {
+		return err
+	}
+	// A rename removes the old key before the new one is written
+	if c.Before != nil && (c.After == nil || c.After.Name != c.Before.Name) {
+		if _, err := tx.Exec(`DELETE FROM boss_metadata WHERE name=?`, c.Before.Name); err != nil {
+			return err
+		}
+	}
+	boss := ""
+	if c.After != nil {
+		boss = c.After.Name
+		if _, err := tx.Exec(`INSERT INTO boss_metadata (name, doc, updated_at, updated_by) VALUES (?, ?, ?, ?)
+			ON CONFLICT(name) DO UPDATE SET doc=excluded.doc, updated_at=excluded.updated_at, updated_by=excluded.updated_by`,
+			boss, after.String, now, actor); err != nil {
+			return err
+		}
+	} else {
+		boss = c.Before.Name
+	}
+	_, err = tx.Exec(`INSERT INTO metadata_audit (actor, action, boss, before_doc, after_doc, at) VALUES (?, ?, ?, ?, ?, ?)`,
+		actor, c.Action(), boss, before, after, now)
+	return err
+}
+
+// ListMetadataAudit returns the newest audit entries first, optionally for one boss
+func (s *SQLite) ListMetadataAudit(boss string, limit int) ([]models.MetadataAuditEntry, error) {
+	defer observe("list_metadata_audit", time.Now())
+	q := `SELECT id, actor, action, boss, before_doc, after_doc, at FROM metadata_audit`
+	var args []any
+	if boss != "" {
+		q += ` WHERE boss=?`
+		args = append(args, boss)
+	}
+	q += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := s.read.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []models.MetadataAuditEntry
+	for rows.Next() {
+		var e models.MetadataAuditEntry
+		var before, after sql.NullString
+		if err := rows.Scan(&e.ID, &e.Actor, &e.Action, &e.Boss, &before, &after, &e.At); err != nil {
+			return nil, err
+		}
+		if e.Before, err = decodeDoc(before); err != nil {
+			return nil, err
+		}
+		if e.After, err = decodeDoc(after); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// GetSetting returns a stored setting, or "" when it is not set
+func (s *SQLite) GetSetting(key string) (string, error) {
+	defer observe("get_setting", time.Now())
+	var v string
+	err := s.read.QueryRow(`SELECT value FROM settings WHERE key=?`, key).Scan(&v)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return v, err
+}
+
+func (s *SQLite) SetSetting(key, value string) error {
+	defer observe("set_setting", time.Now())
+	_, err := s.DB.Exec(`INSERT INTO settings (key, value) VALUES (?, ?) ON CONFLICT(key) DO UPDATE SET value=excluded.value`, key, value)
+	return err
+}
+
+func encodeDoc(meta *models.BossMetadata) (sql.NullString, error) {
+	if meta == nil {
+		return sql.NullString{}, nil
+	}
+	b, err := json.Marshal(meta)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(b), Valid: true}, nil
+}
+
+func decodeDoc(doc sql.NullString) (*models.BossMetadata, error) {
+	if !doc.Valid {
+		return nil, nil
+	}
+	var meta models.BossMetadata
+	if err := json.Unmarshal([]byte(doc.String), &meta); err != nil {
+		return nil, err
+	}
+	return &meta, nil
+}
@@ -0,0 +1,190 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"tibia-nemesis-api/internal/models"
+)
+
+// migrations run once, in order, after the schema is created. The number of
+// applied migrations is tracked in PRAGMA user_version, so entries must only
+// ever be appended.
+var migrations = []struct {
+	name string
+	up   func(tx *sql.Tx) error
+}{
+	{"merge duplicate-cased worlds", mergeWorldCasing},
+	{"seed observations and kills from spawn chances", seedObservations},
+	{"store observations as runs", addObservationRuns},
+	{"keep the scrapes of observation runs", addRunOffsets},
+	{"remember whether watches held", addWatchHeld},
+}
+
+// SchemaVersion is the user_version of a fully migrated database
+func SchemaVersion() int { return len(migrations) }
+
+// canonicalWorldSQL capitalizes a world column. Tibia world names are a single
+// capitalized word ("Antica"), so this matches the world registry's casing.
+const canonicalWorldSQL = `upper(substr(trim(world), 1, 1)) || lower(substr(trim(world), 2))`
+
+func (s *SQLite) migrate() error {
+	var version int
+	if err := s.DB.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return err
+	}
+	for i := version; i < len(migrations); i++ {
+		m := migrations[i]
+		tx, err := s.DB.Begin()
+		if err != nil {
+			return err
+		}
+		if err := m.up(tx); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d (%s): %w", i+1, m.name, err)
+		}
+		// PRAGMA does not take bind parameters
+		if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, i+1)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		slog.Info("store: applied migration", "version", i+1, "name", m.name)
+	}
+	return nil
+}
+
+// mergeWorldCasing collapses worlds stored under different casings ("antica",
+// "ANTICA") into one canonical world, keeping the most recent row per boss.
+func mergeWorldCasing(tx *sql.Tx) error {
+	res, err := tx.Exec(`DELETE FROM spawn_chances WHERE id NOT IN (
+		SELECT id FROM (
+			SELECT id, ROW_NUMBER() OVER (PARTITION BY lower(trim(world)), name ORDER BY updated_at DESC, id DESC) AS rn
+			FROM spawn_chances
+		) WHERE rn = 1
+	)`)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		slog.Info("store: removed duplicate-cased world rows", "rows", n)
+	}
+	for _, table := range []string{"spawn_chances", "watches"} {
+		if _, err := tx.Exec(`UPDATE ` + table + ` SET world = ` + canonicalWorldSQL); err != nil {
+			return err
+		}
+	}
+	_, err = tx.Exec(`UPDATE webhooks SET world = ` + canonicalWorldSQL + ` WHERE world != ''`)
+	return err
+}
+
+// seedObservations starts the observation history and kill log with the
+// latest scrape of every boss, which is all that was kept before them
+func seedObservations(tx *sql.Tx) error {
+	rows, err := tx.Query(`SELECT world, name, percent, days_since_kill, is_no_chance, updated_at FROM spawn_chances`)
+	if err != nil {
+		return err
+	}
+	var entries []models.SpawnChance
+	for rows.Next() {
+		var e models.SpawnChance
+		var percent, days sql.NullInt64
+		var isNoChance int
+		if err := rows.Scan(&e.World, &e.Name, &percent, &days, &isNoChance, &e.UpdatedAt); err != nil {
+			rows.Close()
+			return err
+		}
+		e.Percent, e.DaysSinceKill, e.IsNoChance = intPtr(percent), intPtr(days), isNoChance == 1
+		entries = append(entries, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rec, err := newObservationRecorder(tx)
+	if err != nil {
+		return err
+	}
+	defer rec.Close()
+	for _, e := range entries {
+		if err := rec.record(e.World, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addObservationRuns adds observations.seen_until, which the schema already
+// has when the observations table was created after it
+func addObservationRuns(tx *sql.Tx) error {
+	var n int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('observations') WHERE name='seen_until'`).Scan(&n); err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+	_, err := tx.Exec(`ALTER TABLE observations ADD COLUMN seen_until TIMESTAMP NULL`)
+	return err
+}
+
+// addRunOffsets adds observations.seen_offsets. Runs recorded before it only
+// kept their last scrape; they get the daily series they read back as until
+// now: one scrape per day at the stored row's time of day, then the last.
+func addRunOffsets(tx *sql.Tx) error {
+	var n int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('observations') WHERE name='seen_offsets'`).Scan(&n); err != nil {
+		return err
+	}
+	if n == 0 {
+		if _, err := tx.Exec(`ALTER TABLE observations ADD COLUMN seen_offsets TEXT NULL`); err != nil {
+			return err
+		}
+	}
+	rows, err := tx.Query(`SELECT id, observed_at, seen_until FROM observations WHERE seen_until IS NOT NULL AND seen_offsets IS NULL`)
+	if err != nil {
+		return err
+	}
+	offsets := make(map[int64]any)
+	for rows.Next() {
+		var id int64
+		var observed, until time.Time
+		if err := rows.Scan(&id, &observed, &until); err != nil {
+			rows.Close()
+			return err
+		}
+		var seen []time.Duration
+		for k := 1; k < daysBetween(observed, until); k++ {
+			seen = append(seen, observed.AddDate(0, 0, k).Sub(observed))
+		}
+		offsets[id] = encodeOffsets(append(seen, until.Sub(observed)))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for id, seen := range offsets {
+		if _, err := tx.Exec(`UPDATE observations SET seen_offsets=? WHERE id=?`, seen, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addWatchHeld adds watches.held, which the schema already has when the
+// watches table was created after it
+func addWatchHeld(tx *sql.Tx) error {
+	var n int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('watches') WHERE name='held'`).Scan(&n); err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+	_, err := tx.Exec(`ALTER TABLE watches ADD COLUMN held INTEGER NOT NULL DEFAULT 0`)
+	return err
+}
@@ -0,0 +1,186 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"tibia-nemesis-api/internal/models"
+)
+
+// Downsampling modes for observations past the retention period
+const (
+	DownsampleDaily   = "daily"   // Keep the last observation of each boss per day
+	DownsampleChanges = "changes" // Fold observations that only confirm the one before into its run
+)
+
+// DownsampleObservations thins out observations older than before and returns
+// the number of rows removed. Both modes work on whole runs, never on rows
+// alone: the runs that start before the cutoff are expanded, thinned out by
+// daily, and stored as runs again. Kills are derived when observations are
+// recorded and live in their own table, so they are never affected. Running it
+// again over the same period removes nothing more.
+func (s *SQLite) DownsampleObservations(before time.Time, mode string) (int64, error) {
+	defer observe("downsample_observations", time.Now())
+	switch mode {
+	case DownsampleDaily:
+		return s.rewriteRuns(before, func(obs []models.SpawnChance) []models.SpawnChance {
+			return lastPerDay(obs, before)
+		})
+	case DownsampleChanges:
+		return s.mergeRuns(before)
+	}
+	return 0, fmt.Errorf("unknown downsampling mode %q", mode)
+}
+
+// mergeRuns re-encodes the observations of runs starting before the cutoff
+// the way observationRecorder stores new scrapes. Stored observations from
+// before runs existed are compacted this way; what they read back as is
+// unchanged.
+func (s *SQLite) mergeRuns(before time.Time) (int64, error) {
+	return s.rewriteRuns(before, nil)
+}
+
+// rewriteRuns expands the runs of each boss that start before the cutoff,
+// passes the observations to keep, when not nil, and stores what it returns
+// as runs again. Bosses whose runs would not change are left alone. It
+// returns the number of rows removed. The runs are read in the write
+// transaction, so a refresh extending one of them waits instead of being
+// overwritten by a stale copy.
+func (s *SQLite) rewriteRuns(before time.Time, keep func([]models.SpawnChance) []models.SpawnChance) (int64, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	rows, err := tx.Query(`SELECT id, `+observationColumns+` FROM observations WHERE observed_at < ? ORDER BY world, name, observed_at, id`, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	var deleted []int64
+	var inserted []observationRun
+	var group []observationRun
+	flush := func() {
+		if len(group) == 0 {
+			return
+		}
+		var obs []models.SpawnChance
+		for _, r := range group {
+			obs = append(obs, r.expand()...)
+		}
+		sort.SliceStable(obs, func(i, j int) bool { return obs[i].UpdatedAt.Before(obs[j].UpdatedAt) })
+		n := len(obs)
+		if keep != nil {
+			obs = keep(obs)
+		}
+		runs := encodeRuns(obs)
+		if len(runs) < len(group) || len(obs) < n {
+			for _, r := range group {
+				deleted = append(deleted, r.ID)
+			}
+			inserted = append(inserted, runs...)
+		}
+		group = group[:0]
+	}
+	for rows.Next() {
+		r, err := scanRun(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if len(group) > 0 && (group[0].World != r.World || group[0].Name != r.Name) {
+			flush()
+		}
+		group = append(group, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	flush()
+	if len(deleted) == 0 {
+		return 0, nil
+	}
+
+	for _, id := range deleted {
+		if _, err := tx.Exec(`DELETE FROM observations WHERE id=?`, id); err != nil {
+			return 0, err
+		}
+	}
+	for _, r := range inserted {
+		var until any
+		if len(r.Seen) > 0 {
+			until = r.SeenUntil
+		}
+		_, err := tx.Exec(`INSERT INTO observations (world, name, percent, days_since_kill, is_no_chance, observed_at, seen_until, seen_offsets) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			r.World, r.Name, nullInt(r.Percent), nullInt(r.DaysSinceKill), boolInt(r.IsNoChance), r.UpdatedAt, until, encodeOffsets(r.Seen))
+		if err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(deleted) - len(inserted)), tx.Commit()
+}
+
+// lastPerDay drops observations before the cutoff that are followed by another
+// one on the same UTC day. obs is sorted oldest first.
+func lastPerDay(obs []models.SpawnChance, before time.Time) []models.SpawnChance {
+	out := obs[:0]
+	for i, o := range obs {
+		if o.UpdatedAt.Before(before) && i+1 < len(obs) && daysBetween(o.UpdatedAt, obs[i+1].UpdatedAt) == 0 {
+			continue
+		}
+		out = append(out, o)
+	}
+	return out
+}
+
+// encodeRuns stores observations of one boss, oldest first, as runs
+func encodeRuns(obs []models.SpawnChance) []observationRun {
+	var runs []observationRun
+	for _, o := range obs {
+		if n := len(runs); n > 0 && runs[n-1].continues(o) {
+			runs[n-1].add(o.UpdatedAt)
+			continue
+		}
+		runs = append(runs, observationRun{SpawnChance: o})
+	}
+	return runs
+}
+
+// CountObservations returns the number of stored observations
+func (s *SQLite) CountObservations() (int64, error) {
+	defer observe("count_observations", time.Now())
+	var n int64
+	err := s.read.QueryRow(`SELECT COUNT(*) FROM observations`).Scan(&n)
+	return n, err
+}
+
+// Optimize rebuilds the database file to return freed pages to the file
+// system and refreshes the query planner statistics. It returns the file size
+// before and after. VACUUM blocks writers while it runs.
+func (s *SQLite) Optimize() (before, after int64, err error) {
+	defer observe("optimize", time.Now())
+	if before, err = s.size(); err != nil {
+		return 0, 0, err
+	}
+	if _, err = s.DB.Exec(`VACUUM`); err != nil {
+		return before, 0, err
+	}
+	if _, err = s.DB.Exec(`ANALYZE`); err != nil {
+		return before, 0, err
+	}
+	after, err = s.size()
+	return before, after, err
+}
+
+// size returns the size of the database in bytes
+func (s *SQLite) size() (int64, error) {
+	var pages, pageSize int64
+	if err := s.DB.QueryRow(`PRAGMA page_count`).Scan(&pages); err != nil {
+		return 0, err
+	}
+	if err := s.DB.QueryRow(`PRAGMA page_size`).Scan(&pageSize); err != nil {
+		return 0, err
+	}
+	return pages * pageSize, nil
+}
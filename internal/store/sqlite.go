@@ -3,52 +3,242 @@ package store
 import (
 	"database/sql"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
+	"tibia-nemesis-api/internal/metrics"
 	"tibia-nemesis-api/internal/models"
 
 	_ "modernc.org/sqlite"
 )
 
+// SQLite stores everything in one database file in WAL mode, so readers never
+// wait for the writer. Writes go through a single connection and queue in
+// the pool instead of failing with SQLITE_BUSY; reads use a separate
+// read-only pool.
 type SQLite struct {
-	DB *sql.DB
+	DB   *sql.DB // Writer: one connection, for writes and transactions
+	read *sql.DB // Read-only pool
+
+	// Prepared statements of the hot read paths
+	spawnChances *sql.Stmt
+	bossHistory  *sql.Stmt
+	kills        *sql.Stmt
+}
+
+var queryDuration = metrics.NewHistogram("tibia_nemesis_db_query_duration_seconds",
+	"Duration of database operations", metrics.QueryBuckets, "op")
+
+// observe records the duration of the operation op started at start
+func observe(op string, start time.Time) {
+	queryDuration.Observe(time.Since(start).Seconds(), op)
+}
+
+// Options tune the connections. Zero values use the defaults.
+type Options struct {
+	BusyTimeout time.Duration // How long a statement waits for a lock held elsewhere (default 5s)
+	Synchronous string        // PRAGMA synchronous: OFF, NORMAL (default), FULL or EXTRA
+	ReadConns   int           // Size of the read pool (default 4)
 }
 
-func NewSQLite(path string) (*SQLite, error) {
+func NewSQLite(path string, opts Options) (*SQLite, error) {
 	if path == "" {
 		path = "tibia-nemesis-api.db"
 	}
-	db, err := sql.Open("sqlite", path)
+	if opts.BusyTimeout <= 0 {
+		opts.BusyTimeout = 5 * time.Second
+	}
+	if opts.ReadConns <= 0 {
+		opts.ReadConns = 4
+	}
+	switch opts.Synchronous = strings.ToUpper(opts.Synchronous); opts.Synchronous {
+	case "":
+		// NORMAL is durable in WAL mode except for the last commits on power loss
+		opts.Synchronous = "NORMAL"
+	case "OFF", "NORMAL", "FULL", "EXTRA":
+	default:
+		return nil, fmt.Errorf("unknown synchronous mode %q", opts.Synchronous)
+	}
+	pragmas := fmt.Sprintf("_pragma=foreign_keys(1)&_pragma=busy_timeout(%d)&_pragma=synchronous(%s)",
+		opts.BusyTimeout.Milliseconds(), opts.Synchronous)
+
+	// The writer comes first: it creates the file and switches it to WAL,
+	// which read-only connections cannot do. Immediate transactions take the
+	// write lock up front, so a transaction never fails halfway for a lock
+	// another process holds.
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=journal_mode(WAL)&_txlock=immediate&"+pragmas)
 	if err != nil {
 		return nil, err
 	}
+	db.SetMaxOpenConns(1)
 	s := &SQLite{DB: db}
 	if err := s.init(); err != nil {
 		_ = db.Close()
 		return nil, err
 	}
+	if s.read, err = sql.Open("sqlite", "file:"+path+"?mode=ro&"+pragmas); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	s.read.SetMaxOpenConns(opts.ReadConns)
+	if err := s.prepare(); err != nil {
+		_ = s.Close()
+		return nil, err
+	}
 	return s, nil
 }
 
-func (s *SQLite) Close() error { return s.DB.Close() }
+func (s *SQLite) prepare() error {
+	var err error
+	if s.spawnChances, err = s.read.Prepare(`SELECT name, percent, days_since_kill, is_no_chance, updated_at FROM spawn_chances WHERE world=? ORDER BY name ASC`); err != nil {
+		return err
+	}
+	if s.bossHistory, err = s.read.Prepare(`SELECT id, ` + observationColumns + ` FROM observations WHERE world=? AND name=? ORDER BY observed_at DESC, id DESC LIMIT ?`); err != nil {
+		return err
+	}
+	s.kills, err = s.read.Prepare(`SELECT world, name, killed_on, observed_at FROM kills WHERE world=? AND name=? ORDER BY killed_on DESC LIMIT ?`)
+	return err
+}
+
+func (s *SQLite) Close() error {
+	defer observe("close", time.Now())
+	for _, stmt := range []*sql.Stmt{s.spawnChances, s.bossHistory, s.kills} {
+		if stmt != nil {
+			stmt.Close()
+		}
+	}
+	if s.read != nil {
+		s.read.Close()
+	}
+	return s.DB.Close()
+}
+
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS spawn_chances (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		world TEXT NOT NULL,
+		name TEXT NOT NULL,
+		percent INTEGER NULL,
+		days_since_kill INTEGER NULL,
+		is_no_chance INTEGER NOT NULL DEFAULT 0,
+		updated_at TIMESTAMP NOT NULL,
+		UNIQUE(world, name)
+	);`,
+	`CREATE TABLE IF NOT EXISTS webhooks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		url TEXT NOT NULL,
+		secret TEXT NOT NULL,
+		events TEXT NOT NULL,
+		world TEXT NOT NULL DEFAULT '',
+		bosses TEXT NOT NULL DEFAULT '[]',
+		threshold_percent INTEGER NULL,
+		active INTEGER NOT NULL DEFAULT 1,
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	);`,
+	`CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		webhook_id INTEGER NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+		event TEXT NOT NULL,
+		payload TEXT NOT NULL,
+		status TEXT NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at TIMESTAMP NULL,
+		last_error TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP NOT NULL,
+		delivered_at TIMESTAMP NULL
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(status, next_attempt_at);`,
+	`CREATE TABLE IF NOT EXISTS webhook_delivery_attempts (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		delivery_id INTEGER NOT NULL REFERENCES webhook_deliveries(id) ON DELETE CASCADE,
+		attempt INTEGER NOT NULL,
+		status_code INTEGER NULL,
+		error TEXT NOT NULL DEFAULT '',
+		duration_ms INTEGER NOT NULL,
+		attempted_at TIMESTAMP NOT NULL
+	);`,
+	`CREATE TABLE IF NOT EXISTS subscribers (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		external_id TEXT NOT NULL UNIQUE,
+		created_at TIMESTAMP NOT NULL
+	);`,
+	`CREATE TABLE IF NOT EXISTS watches (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		subscriber_id INTEGER NOT NULL REFERENCES subscribers(id) ON DELETE CASCADE,
+		world TEXT NOT NULL,
+		boss TEXT NOT NULL,
+		condition TEXT NOT NULL,
+		value INTEGER NULL,
+		held INTEGER NOT NULL DEFAULT 0, -- Whether the condition held at the last evaluation
+		created_at TIMESTAMP NOT NULL
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_watches_world ON watches(world);`,
+	`CREATE TABLE IF NOT EXISTS alerts (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		watch_id INTEGER NOT NULL REFERENCES watches(id) ON DELETE CASCADE,
+		percent INTEGER NULL,
+		days_since_kill INTEGER NULL,
+		cycle TEXT NOT NULL,
+		triggered_at TIMESTAMP NOT NULL,
+		UNIQUE(watch_id, cycle)
+	);`,
+	`CREATE TABLE IF NOT EXISTS boss_metadata (
+		name TEXT PRIMARY KEY,
+		doc TEXT NOT NULL,
+		updated_at TIMESTAMP NOT NULL,
+		updated_by TEXT NOT NULL
+	);`,
+	`CREATE TABLE IF NOT EXISTS metadata_audit (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		actor TEXT NOT NULL,
+		action TEXT NOT NULL,
+		boss TEXT NOT NULL,
+		before_doc TEXT NULL,
+		after_doc TEXT NULL,
+		at TIMESTAMP NOT NULL
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_metadata_audit_boss ON metadata_audit(boss);`,
+	`CREATE TABLE IF NOT EXISTS observations (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		world TEXT NOT NULL,
+		name TEXT NOT NULL,
+		percent INTEGER NULL,
+		days_since_kill INTEGER NULL,
+		is_no_chance INTEGER NOT NULL DEFAULT 0,
+		observed_at TIMESTAMP NOT NULL,
+		seen_until TIMESTAMP NULL, -- Last later scrape that matched this row, see observationRun
+		seen_offsets TEXT NULL -- Every such scrape, as nanoseconds after observed_at
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_observations_boss ON observations(world, name, observed_at);`,
+	`CREATE INDEX IF NOT EXISTS idx_observations_observed ON observations(observed_at);`, // Retention cutoffs and export ranges
+	`CREATE TABLE IF NOT EXISTS kills (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		world TEXT NOT NULL,
+		name TEXT NOT NULL,
+		killed_on TEXT NOT NULL, -- YYYY-MM-DD, UTC
+		observed_at TIMESTAMP NOT NULL,
+		UNIQUE(world, name, killed_on)
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_kills_name ON kills(name, world, killed_on);`,
+	`CREATE TABLE IF NOT EXISTS settings (
+		key TEXT PRIMARY KEY,
+		value TEXT NOT NULL
+	);`,
+}
 
 func (s *SQLite) init() error {
-	_, err := s.DB.Exec(`
-		CREATE TABLE IF NOT EXISTS spawn_chances (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			world TEXT NOT NULL,
-			name TEXT NOT NULL,
-			percent INTEGER NULL,
-			days_since_kill INTEGER NULL,
-			is_no_chance INTEGER NOT NULL DEFAULT 0,
-			updated_at TIMESTAMP NOT NULL,
-			UNIQUE(world, name)
-		);
-	`)
-	return err
+	for _, stmt := range schema {
+		if _, err := s.DB.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return s.migrate()
 }
 
 func (s *SQLite) UpsertSpawnChances(world string, entries []models.SpawnChance) error {
+	defer observe("upsert_spawn_chances", time.Now())
 	if world == "" {
 		return errors.New("world required")
 	}
@@ -66,6 +256,12 @@ func (s *SQLite) UpsertSpawnChances(world string, entries []models.SpawnChance)
 		return err
 	}
 	defer stmt.Close()
+	rec, err := newObservationRecorder(tx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer rec.Close()
 	for _, e := range entries {
 		var p, d interface{}
 		if e.Percent != nil {
@@ -86,12 +282,17 @@ func (s *SQLite) UpsertSpawnChances(world string, entries []models.SpawnChance)
 			tx.Rollback()
 			return err
 		}
+		if err := rec.record(world, e); err != nil {
+			tx.Rollback()
+			return err
+		}
 	}
 	return tx.Commit()
 }
 
 func (s *SQLite) GetSpawnChances(world string) ([]models.SpawnChance, error) {
-	rows, err := s.DB.Query(`SELECT name, percent, days_since_kill, is_no_chance, updated_at FROM spawn_chances WHERE world=? ORDER BY name ASC`, world)
+	defer observe("get_spawn_chances", time.Now())
+	rows, err := s.spawnChances.Query(world)
 	if err != nil {
 		return nil, err
 	}
@@ -128,59 +329,42 @@ func (s *SQLite) GetSpawnChances(world string) ([]models.SpawnChance, error) {
 	return out, nil
 }
 
-func (s *SQLite) GetWorlds() ([]string, error) {
-	rows, err := s.DB.Query(`SELECT DISTINCT world FROM spawn_chances ORDER BY world ASC`)
+// LastUpdated returns when each world's spawn chances were last scraped
+func (s *SQLite) LastUpdated() (map[string]time.Time, error) {
+	defer observe("last_updated", time.Now())
+	rows, err := s.read.Query(`SELECT world, updated_at FROM spawn_chances`)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var worlds []string
+	out := make(map[string]time.Time)
 	for rows.Next() {
-		var w string
-		if err := rows.Scan(&w); err != nil {
+		var world string
+		var updated time.Time
+		if err := rows.Scan(&world, &updated); err != nil {
 			return nil, err
 		}
-		worlds = append(worlds, w)
+		if updated.After(out[world]) {
+			out[world] = updated
+		}
 	}
-	return worlds, nil
+	return out, rows.Err()
 }
 
-func (s *SQLite) GetBossHistory(world, name string, limit int) ([]models.SpawnChance, error) {
-	if limit <= 0 {
-		limit = 25
-	}
-	rows, err := s.DB.Query(`SELECT percent, days_since_kill, is_no_chance, updated_at FROM spawn_chances WHERE world=? AND name=? ORDER BY updated_at DESC LIMIT ?`, world, name, limit)
+func (s *SQLite) GetWorlds() ([]string, error) {
+	defer observe("get_worlds", time.Now())
+	rows, err := s.read.Query(`SELECT DISTINCT world FROM spawn_chances ORDER BY world ASC`)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var out []models.SpawnChance
+	worlds := []string{} // Encodes as [] rather than null without data
 	for rows.Next() {
-		var percent sql.NullInt64
-		var daysSinceKill sql.NullInt64
-		var isNoChance int
-		var updated time.Time
-		if err := rows.Scan(&percent, &daysSinceKill, &isNoChance, &updated); err != nil {
+		var w string
+		if err := rows.Scan(&w); err != nil {
 			return nil, err
 		}
-		var p *int
-		if percent.Valid {
-			v := int(percent.Int64)
-			p = &v
-		}
-		var d *int
-		if daysSinceKill.Valid {
-			v := int(daysSinceKill.Int64)
-			d = &v
-		}
-		out = append(out, models.SpawnChance{
-			World:         world,
-			Name:          name,
-			Percent:       p,
-			DaysSinceKill: d,
-			IsNoChance:    isNoChance == 1,
-			UpdatedAt:     updated,
-		})
+		worlds = append(worlds, w)
 	}
-	return out, nil
+	return worlds, rows.Err()
 }
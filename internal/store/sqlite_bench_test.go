@@ -0,0 +1,94 @@
+package store
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"tibia-nemesis-api/internal/models"
+)
+
+// Roughly a full deployment: every world scraped with all of its bosses
+const (
+	benchWorlds = 100
+	benchBosses = 100
+)
+
+func benchWorld(i int) string { return fmt.Sprintf("World%03d", i) }
+
+// benchScrape returns a world's scrape on day: most bosses confirm the day
+// before, every seventh was killed
+func benchScrape(day int, at time.Time) []models.SpawnChance {
+	out := make([]models.SpawnChance, benchBosses)
+	for b := range out {
+		days := (day + b) % 30
+		if b%7 == 0 {
+			days = day % 5
+		}
+		out[b] = models.SpawnChance{
+			Name:          fmt.Sprintf("Boss %03d", b),
+			Percent:       intp(b % 50),
+			DaysSinceKill: intp(days),
+			UpdatedAt:     at,
+		}
+	}
+	return out
+}
+
+// seedBench stores two days of scrapes of every world
+func seedBench(b *testing.B, s *SQLite, start time.Time) {
+	b.Helper()
+	for day := 0; day < 2; day++ {
+		for w := 0; w < benchWorlds; w++ {
+			if err := s.UpsertSpawnChances(benchWorld(w), benchScrape(day, start.AddDate(0, 0, day))); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkGetSpawnChances(b *testing.B) {
+	s := openTestStore(b)
+	seedBench(b, s, time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		list, err := s.GetSpawnChances(benchWorld(i % benchWorlds))
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(list) != benchBosses {
+			b.Fatalf("got %d bosses, want %d", len(list), benchBosses)
+		}
+	}
+}
+
+func BenchmarkGetSpawnChancesParallel(b *testing.B) {
+	s := openTestStore(b)
+	seedBench(b, s, time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC))
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			if _, err := s.GetSpawnChances(benchWorld(i % benchWorlds)); err != nil {
+				b.Error(err)
+				return
+			}
+			i++
+		}
+	})
+}
+
+// BenchmarkUpsertSpawnChances stores one world's scrape per iteration, a day
+// after that world's previous one, so runs are extended as in production
+func BenchmarkUpsertSpawnChances(b *testing.B) {
+	s := openTestStore(b)
+	start := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	seedBench(b, s, start)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		day := 2 + i/benchWorlds
+		if err := s.UpsertSpawnChances(benchWorld(i%benchWorlds), benchScrape(day, start.AddDate(0, 0, day))); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
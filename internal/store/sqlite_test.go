@@ -0,0 +1,30 @@
+package store
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"tibia-nemesis-api/internal/models"
+)
+
+func TestGetWorlds(t *testing.T) {
+	s := openTestStore(t)
+	worlds, err := s.GetWorlds()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := json.Marshal(worlds); string(b) != "[]" {
+		t.Errorf("no worlds encode as %s, want []", b)
+	}
+
+	now := time.Now().UTC()
+	for _, world := range []string{"Secura", "Antica"} {
+		if err := s.UpsertSpawnChances(world, []models.SpawnChance{{Name: "Furyosa", DaysSinceKill: intp(3), UpdatedAt: now}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if worlds, err = s.GetWorlds(); err != nil || len(worlds) != 2 || worlds[0] != "Antica" || worlds[1] != "Secura" {
+		t.Errorf("got %v, %v; want [Antica Secura]", worlds, err)
+	}
+}
@@ -0,0 +1,173 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+
+	"tibia-nemesis-api/internal/models"
+)
+
+const watchSelect = `SELECT w.id, s.external_id, w.world, w.boss, w.condition, w.value, w.created_at, w.held
+	FROM watches w JOIN subscribers s ON s.id = w.subscriber_id`
+
+// CreateWatch stores a watch, registering its subscriber on first use
+func (s *SQLite) CreateWatch(w *models.Watch) error {
+	defer observe("create_watch", time.Now())
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	if _, err := tx.Exec(`INSERT OR IGNORE INTO subscribers (external_id, created_at) VALUES (?, ?)`, w.Subscriber, now); err != nil {
+		tx.Rollback()
+		return err
+	}
+	var subscriberID int64
+	if err := tx.QueryRow(`SELECT id FROM subscribers WHERE external_id=?`, w.Subscriber).Scan(&subscriberID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	res, err := tx.Exec(`INSERT INTO watches (subscriber_id, world, boss, condition, value, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		subscriberID, w.World, w.Boss, w.Condition, nullInt(w.Value), now)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if w.ID, err = res.LastInsertId(); err != nil {
+		tx.Rollback()
+		return err
+	}
+	w.CreatedAt = now
+	return tx.Commit()
+}
+
+// DeleteWatch removes a watch owned by subscriber
+func (s *SQLite) DeleteWatch(subscriber string, id int64) error {
+	defer observe("delete_watch", time.Now())
+	res, err := s.DB.Exec(`DELETE FROM watches WHERE id=? AND subscriber_id=(SELECT id FROM subscribers WHERE external_id=?)`, id, subscriber)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *SQLite) ListWatches(subscriber string) ([]models.Watch, error) {
+	defer observe("list_watches", time.Now())
+	rows, err := s.read.Query(watchSelect+` WHERE s.external_id=? ORDER BY w.id ASC`, subscriber)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanWatches(rows)
+}
+
+// WatchesForWorld returns every watch on world (case-insensitive)
+func (s *SQLite) WatchesForWorld(world string) ([]models.Watch, error) {
+	defer observe("watches_for_world", time.Now())
+	rows, err := s.read.Query(watchSelect+` WHERE w.world=? COLLATE NOCASE ORDER BY w.id ASC`, world)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanWatches(rows)
+}
+
+// SetWatchHeld records whether a watch's condition held at its evaluation
+func (s *SQLite) SetWatchHeld(id int64, held bool) error {
+	defer observe("set_watch_held", time.Now())
+	_, err := s.DB.Exec(`UPDATE watches SET held=? WHERE id=?`, held, id)
+	return err
+}
+
+// InsertAlert records a triggered watch for cycle. It reports false when
+// the watch already fired in that cycle.
+func (s *SQLite) InsertAlert(a *models.Alert) (bool, error) {
+	defer observe("insert_alert", time.Now())
+	res, err := s.DB.Exec(`INSERT OR IGNORE INTO alerts (watch_id, percent, days_since_kill, cycle, triggered_at) VALUES (?, ?, ?, ?, ?)`,
+		a.WatchID, nullInt(a.Percent), nullInt(a.DaysSinceKill), a.Cycle, a.TriggeredAt.UTC())
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil || n == 0 {
+		return false, err
+	}
+	a.ID, err = res.LastInsertId()
+	return true, err
+}
+
+// AlertFilter narrows ListAlerts; zero values match everything
+type AlertFilter struct {
+	Subscriber string
+	World      string
+	Since      time.Time
+	Limit      int
+}
+
+func (s *SQLite) ListAlerts(f AlertFilter) ([]models.Alert, error) {
+	defer observe("list_alerts", time.Now())
+	if f.Limit <= 0 {
+		f.Limit = 100
+	}
+	q := `SELECT a.id, a.watch_id, s.external_id, w.world, w.boss, w.condition, w.value, a.percent, a.days_since_kill, a.cycle, a.triggered_at
+		FROM alerts a JOIN watches w ON w.id = a.watch_id JOIN subscribers s ON s.id = w.subscriber_id WHERE 1=1`
+	var args []any
+	if f.Subscriber != "" {
+		q += ` AND s.external_id=?`
+		args = append(args, f.Subscriber)
+	}
+	if f.World != "" {
+		q += ` AND w.world=? COLLATE NOCASE`
+		args = append(args, f.World)
+	}
+	if !f.Since.IsZero() {
+		q += ` AND a.triggered_at >= ?`
+		args = append(args, f.Since.UTC())
+	}
+	q += ` ORDER BY a.triggered_at DESC, a.id DESC LIMIT ?`
+	args = append(args, f.Limit)
+
+	rows, err := s.read.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []models.Alert{}
+	for rows.Next() {
+		var a models.Alert
+		var value, percent, days sql.NullInt64
+		if err := rows.Scan(&a.ID, &a.WatchID, &a.Subscriber, &a.World, &a.Boss, &a.Condition, &value, &percent, &days, &a.Cycle, &a.TriggeredAt); err != nil {
+			return nil, err
+		}
+		a.Value = intPtr(value)
+		a.Percent = intPtr(percent)
+		a.DaysSinceKill = intPtr(days)
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+func scanWatches(rows *sql.Rows) ([]models.Watch, error) {
+	out := []models.Watch{}
+	for rows.Next() {
+		var w models.Watch
+		var value sql.NullInt64
+		if err := rows.Scan(&w.ID, &w.Subscriber, &w.World, &w.Boss, &w.Condition, &value, &w.CreatedAt, &w.Held); err != nil {
+			return nil, err
+		}
+		w.Value = intPtr(value)
+		out = append(out, w)
+	}
+	return out, rows.Err()
+}
+
+func intPtr(v sql.NullInt64) *int {
+	if !v.Valid {
+		return nil
+	}
+	i := int(v.Int64)
+	return &i
+}
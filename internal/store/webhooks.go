@@ -0,0 +1,288 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"tibia-nemesis-api/internal/models"
+)
+
+// ErrNotFound is returned when a requested row does not exist
+var ErrNotFound = errors.New("not found")
+
+const webhookColumns = `id, url, secret, events, world, bosses, threshold_percent, active, created_at, updated_at`
+
+func (s *SQLite) CreateWebhook(wh *models.Webhook) error {
+	defer observe("create_webhook", time.Now())
+	events, bosses, err := encodeWebhookLists(wh)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	res, err := s.DB.Exec(`INSERT INTO webhooks (url, secret, events, world, bosses, threshold_percent, active, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		wh.URL, wh.Secret, events, wh.World, bosses, nullInt(wh.ThresholdPercent), boolInt(wh.Active), now, now)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	wh.ID = id
+	wh.CreatedAt = now
+	wh.UpdatedAt = now
+	return nil
+}
+
+func (s *SQLite) UpdateWebhook(wh *models.Webhook) error {
+	defer observe("update_webhook", time.Now())
+	events, bosses, err := encodeWebhookLists(wh)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	res, err := s.DB.Exec(`UPDATE webhooks SET url=?, events=?, world=?, bosses=?, threshold_percent=?, active=?, updated_at=? WHERE id=?`,
+		wh.URL, events, wh.World, bosses, nullInt(wh.ThresholdPercent), boolInt(wh.Active), now, wh.ID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	wh.UpdatedAt = now
+	return nil
+}
+
+func (s *SQLite) DeleteWebhook(id int64) error {
+	defer observe("delete_webhook", time.Now())
+	res, err := s.DB.Exec(`DELETE FROM webhooks WHERE id=?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *SQLite) GetWebhook(id int64) (*models.Webhook, error) {
+	defer observe("get_webhook", time.Now())
+	row := s.read.QueryRow(`SELECT `+webhookColumns+` FROM webhooks WHERE id=?`, id)
+	wh, err := scanWebhook(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return wh, err
+}
+
+// ListWebhooks returns all webhooks, or only active ones when activeOnly is set
+func (s *SQLite) ListWebhooks(activeOnly bool) ([]models.Webhook, error) {
+	defer observe("list_webhooks", time.Now())
+	q := `SELECT ` + webhookColumns + ` FROM webhooks`
+	if activeOnly {
+		q += ` WHERE active=1`
+	}
+	rows, err := s.read.Query(q + ` ORDER BY id ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []models.Webhook{}
+	for rows.Next() {
+		wh, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *wh)
+	}
+	return out, rows.Err()
+}
+
+// EnqueueDelivery stores a pending delivery that is due immediately
+func (s *SQLite) EnqueueDelivery(d *models.WebhookDelivery) error {
+	defer observe("enqueue_delivery", time.Now())
+	now := time.Now().UTC()
+	res, err := s.DB.Exec(`INSERT INTO webhook_deliveries (webhook_id, event, payload, status, attempts, next_attempt_at, created_at) VALUES (?, ?, ?, ?, 0, ?, ?)`,
+		d.WebhookID, d.Event, d.Payload, models.DeliveryPending, now, now)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	d.ID = id
+	d.Status = models.DeliveryPending
+	d.NextAttemptAt = &now
+	d.CreatedAt = now
+	return nil
+}
+
+// DueDeliveries returns pending deliveries whose next attempt is at or before now
+func (s *SQLite) DueDeliveries(now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	defer observe("due_deliveries", time.Now())
+	rows, err := s.read.Query(`SELECT id, webhook_id, event, payload, status, attempts, next_attempt_at, last_error, created_at, delivered_at
+		FROM webhook_deliveries WHERE status=? AND next_attempt_at <= ? ORDER BY next_attempt_at ASC LIMIT ?`,
+		models.DeliveryPending, now.UTC(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanDeliveries(rows)
+}
+
+func (s *SQLite) ListDeliveries(webhookID int64, limit int) ([]models.WebhookDelivery, error) {
+	defer observe("list_deliveries", time.Now())
+	if limit <= 0 {
+		limit = 25
+	}
+	rows, err := s.read.Query(`SELECT id, webhook_id, event, payload, status, attempts, next_attempt_at, last_error, created_at, delivered_at
+		FROM webhook_deliveries WHERE webhook_id=? ORDER BY id DESC LIMIT ?`, webhookID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanDeliveries(rows)
+}
+
+func (s *SQLite) ListDeliveryAttempts(deliveryID int64) ([]models.DeliveryAttempt, error) {
+	defer observe("list_delivery_attempts", time.Now())
+	rows, err := s.read.Query(`SELECT delivery_id, attempt, status_code, error, duration_ms, attempted_at
+		FROM webhook_delivery_attempts WHERE delivery_id=? ORDER BY attempt ASC`, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []models.DeliveryAttempt{}
+	for rows.Next() {
+		var a models.DeliveryAttempt
+		var code sql.NullInt64
+		if err := rows.Scan(&a.DeliveryID, &a.Attempt, &code, &a.Error, &a.DurationMs, &a.AttemptedAt); err != nil {
+			return nil, err
+		}
+		a.StatusCode = int(code.Int64)
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// RecordDeliveryAttempt logs an attempt and moves the delivery to its next state.
+// A nil next marks the delivery as finished (delivered or failed, depending on status).
+func (s *SQLite) RecordDeliveryAttempt(d *models.WebhookDelivery, a models.DeliveryAttempt, status string, next *time.Time) error {
+	defer observe("record_delivery_attempt", time.Now())
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	var code interface{}
+	if a.StatusCode != 0 {
+		code = a.StatusCode
+	}
+	if _, err := tx.Exec(`INSERT INTO webhook_delivery_attempts (delivery_id, attempt, status_code, error, duration_ms, attempted_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		d.ID, a.Attempt, code, a.Error, a.DurationMs, a.AttemptedAt.UTC()); err != nil {
+		tx.Rollback()
+		return err
+	}
+	var nextAt, deliveredAt interface{}
+	if next != nil {
+		nextAt = next.UTC()
+	}
+	if status == models.DeliveryDelivered {
+		deliveredAt = a.AttemptedAt.UTC()
+	}
+	if _, err := tx.Exec(`UPDATE webhook_deliveries SET status=?, attempts=?, next_attempt_at=?, last_error=?, delivered_at=? WHERE id=?`,
+		status, a.Attempt, nextAt, a.Error, deliveredAt, d.ID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// DropDelivery finishes a delivery without attempting it, with reason as its
+// last error
+func (s *SQLite) DropDelivery(id int64, reason string) error {
+	defer observe("drop_delivery", time.Now())
+	_, err := s.DB.Exec(`UPDATE webhook_deliveries SET status=?, next_attempt_at=NULL, last_error=? WHERE id=?`,
+		models.DeliveryDropped, reason, id)
+	return err
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanWebhook(row rowScanner) (*models.Webhook, error) {
+	var wh models.Webhook
+	var events, bosses string
+	var threshold sql.NullInt64
+	var active int
+	if err := row.Scan(&wh.ID, &wh.URL, &wh.Secret, &events, &wh.World, &bosses, &threshold, &active, &wh.CreatedAt, &wh.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(events), &wh.Events); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(bosses), &wh.Bosses); err != nil {
+		return nil, err
+	}
+	if threshold.Valid {
+		v := int(threshold.Int64)
+		wh.ThresholdPercent = &v
+	}
+	wh.Active = active == 1
+	return &wh, nil
+}
+
+func scanDeliveries(rows *sql.Rows) ([]models.WebhookDelivery, error) {
+	out := []models.WebhookDelivery{}
+	for rows.Next() {
+		var d models.WebhookDelivery
+		var next, delivered sql.NullTime
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.Event, &d.Payload, &d.Status, &d.Attempts, &next, &d.LastError, &d.CreatedAt, &delivered); err != nil {
+			return nil, err
+		}
+		if next.Valid {
+			d.NextAttemptAt = &next.Time
+		}
+		if delivered.Valid {
+			d.DeliveredAt = &delivered.Time
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+func encodeWebhookLists(wh *models.Webhook) (string, string, error) {
+	events, err := json.Marshal(nonNil(wh.Events))
+	if err != nil {
+		return "", "", err
+	}
+	bosses, err := json.Marshal(nonNil(wh.Bosses))
+	if err != nil {
+		return "", "", err
+	}
+	return string(events), string(bosses), nil
+}
+
+func nonNil(v []string) []string {
+	if v == nil {
+		return []string{}
+	}
+	return v
+}
+
+func nullInt(v *int) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
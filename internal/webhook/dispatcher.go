@@ -0,0 +1,293 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"tibia-nemesis-api/internal/metrics"
+	"tibia-nemesis-api/internal/models"
+	"tibia-nemesis-api/internal/store"
+)
+
+const (
+	pollInterval = 5 * time.Second
+	batchSize    = 20
+
+	SignatureHeader = "X-Nemesis-Signature"
+	TimestampHeader = "X-Nemesis-Timestamp"
+	EventHeader     = "X-Nemesis-Event"
+	DeliveryHeader  = "X-Nemesis-Delivery"
+)
+
+var deliveries = metrics.NewCounter("tibia_nemesis_webhook_deliveries_total",
+	"Webhook delivery attempts by outcome: delivered, retry (failed, will be retried), failed (gave up) or dropped (webhook inactive or deleted)", "outcome")
+
+// Envelope is the JSON body POSTed to webhook receivers
+type Envelope struct {
+	ID        string    `json:"id"`
+	Event     string    `json:"event"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// Options tunes delivery retries; zero values use the defaults
+type Options struct {
+	MaxAttempts int           // Attempts before a delivery fails (default 8)
+	Backoff     time.Duration // Delay after the first failed attempt, doubled after each further one (default 30s)
+	MaxBackoff  time.Duration // Longest delay between attempts (default 1h)
+	Timeout     time.Duration // Timeout of each attempt (default 10s)
+}
+
+// Dispatcher fans events out to matching webhooks and delivers them from a persistent queue
+type Dispatcher struct {
+	store  *store.SQLite
+	opts   Options
+	client *http.Client
+	wake   chan struct{}
+}
+
+func NewDispatcher(st *store.SQLite, opts Options) *Dispatcher {
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 8
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = 30 * time.Second
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = time.Hour
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	return &Dispatcher{
+		store:  st,
+		opts:   opts,
+		client: &http.Client{Timeout: opts.Timeout},
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// Emit queues an event for every active webhook subscribed to it.
+// world and boss are used for filtering and may be empty.
+func (d *Dispatcher) Emit(event, world, boss string, data any) {
+	hooks, err := d.store.ListWebhooks(true)
+	if err != nil {
+		slog.Error("webhook: list webhooks", "err", err)
+		return
+	}
+	for _, wh := range hooks {
+		if !Matches(wh, event, world, boss) {
+			continue
+		}
+		if _, err := d.Enqueue(wh, event, data); err != nil {
+			slog.Error("webhook: enqueue failed", "event", event, "webhook_id", wh.ID, "err", err)
+		}
+	}
+}
+
+// Enqueue queues a single delivery for wh regardless of its event subscriptions
+func (d *Dispatcher) Enqueue(wh models.Webhook, event string, data any) (*models.WebhookDelivery, error) {
+	body, err := json.Marshal(Envelope{
+		ID:        newEventID(),
+		Event:     event,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	})
+	if err != nil {
+		return nil, err
+	}
+	del := &models.WebhookDelivery{WebhookID: wh.ID, Event: event, Payload: string(body)}
+	if err := d.store.EnqueueDelivery(del); err != nil {
+		return nil, err
+	}
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+	return del, nil
+}
+
+// Matches reports whether wh wants event for the given world and boss
+func Matches(wh models.Webhook, event, world, boss string) bool {
+	subscribed := false
+	for _, e := range wh.Events {
+		if e == event {
+			subscribed = true
+			break
+		}
+	}
+	if !subscribed {
+		return false
+	}
+	if wh.World != "" && world != "" && !strings.EqualFold(wh.World, world) {
+		return false
+	}
+	if len(wh.Bosses) > 0 && boss != "" {
+		for _, b := range wh.Bosses {
+			if strings.EqualFold(b, boss) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// Run delivers due webhooks until the process exits
+func (d *Dispatcher) Run() {
+	slog.Info("webhook: dispatcher started")
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		d.deliverDue()
+		select {
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+func (d *Dispatcher) deliverDue() {
+	due, err := d.store.DueDeliveries(time.Now().UTC(), batchSize)
+	if err != nil {
+		slog.Error("webhook: load due deliveries", "err", err)
+		return
+	}
+	for i := range due {
+		d.deliver(&due[i])
+	}
+}
+
+// deliver makes the next attempt of a delivery. The webhook is loaded again
+// for every attempt, so deliveries of a webhook that was deactivated or
+// deleted since they were queued are dropped instead of sent.
+func (d *Dispatcher) deliver(del *models.WebhookDelivery) {
+	wh, err := d.store.GetWebhook(del.WebhookID)
+	if errors.Is(err, store.ErrNotFound) {
+		d.drop(del, "webhook deleted")
+		return
+	}
+	if err != nil {
+		slog.Error("webhook: load webhook", "delivery_id", del.ID, "webhook_id", del.WebhookID, "err", err)
+		return
+	}
+	if !wh.Active {
+		d.drop(del, "webhook inactive")
+		return
+	}
+
+	attempt := models.DeliveryAttempt{
+		DeliveryID:  del.ID,
+		Attempt:     del.Attempts + 1,
+		AttemptedAt: time.Now().UTC(),
+	}
+	code, err := d.post(wh, del, attempt.AttemptedAt)
+	attempt.DurationMs = time.Since(attempt.AttemptedAt).Milliseconds()
+	attempt.StatusCode = code
+
+	status := models.DeliveryDelivered
+	var next *time.Time
+	if err != nil {
+		attempt.Error = err.Error()
+		if attempt.Attempt >= d.opts.MaxAttempts {
+			status = models.DeliveryFailed
+			slog.Error("webhook: delivery failed permanently", "delivery_id", del.ID, "url", wh.URL, "attempts", attempt.Attempt, "err", err)
+		} else {
+			status = models.DeliveryPending
+			t := time.Now().UTC().Add(d.Backoff(attempt.Attempt))
+			next = &t
+			slog.Warn("webhook: delivery failed, will retry", "delivery_id", del.ID, "url", wh.URL, "attempt", attempt.Attempt, "retry_at", t, "err", err)
+		}
+	}
+
+	outcome := status
+	if status == models.DeliveryPending {
+		outcome = "retry"
+	}
+	deliveries.Inc(outcome)
+	if err := d.store.RecordDeliveryAttempt(del, attempt, status, next); err != nil {
+		slog.Error("webhook: record delivery", "delivery_id", del.ID, "err", err)
+	}
+}
+
+func (d *Dispatcher) drop(del *models.WebhookDelivery, reason string) {
+	slog.Info("webhook: delivery dropped", "delivery_id", del.ID, "webhook_id", del.WebhookID, "reason", reason)
+	deliveries.Inc(models.DeliveryDropped)
+	if err := d.store.DropDelivery(del.ID, reason); err != nil {
+		slog.Error("webhook: record delivery", "delivery_id", del.ID, "err", err)
+	}
+}
+
+func (d *Dispatcher) post(wh *models.Webhook, del *models.WebhookDelivery, at time.Time) (int, error) {
+	body := []byte(del.Payload)
+	req, err := http.NewRequest(http.MethodPost, wh.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	ts := strconv.FormatInt(at.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "TibiaNemesisAPI/1.0")
+	req.Header.Set(EventHeader, del.Event)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(del.ID, 10))
+	req.Header.Set(TimestampHeader, ts)
+	req.Header.Set(SignatureHeader, Sign(wh.Secret, ts, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign computes the signature header value for a payload.
+// Receivers recompute HMAC-SHA256(secret, timestamp + "." + body) and compare.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Backoff returns the delay before the next attempt after the given attempt number
+func (d *Dispatcher) Backoff(attempt int) time.Duration {
+	wait := d.opts.Backoff
+	for i := 1; i < attempt; i++ {
+		wait *= 2
+		if wait >= d.opts.MaxBackoff {
+			return d.opts.MaxBackoff
+		}
+	}
+	return min(wait, d.opts.MaxBackoff)
+}
+
+// NewSecret generates a random signing secret
+func NewSecret() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func newEventID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
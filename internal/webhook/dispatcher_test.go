@@ -0,0 +1,215 @@
+package webhook
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"tibia-nemesis-api/internal/models"
+	"tibia-nemesis-api/internal/store"
+)
+
+// receiver is a stub webhook endpoint answering with the given statuses in
+// turn, the last one for all further requests
+type receiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newReceiver(t *testing.T, statuses ...int) *receiver {
+	rc := &receiver{statuses: statuses}
+	rc.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rc.mu.Lock()
+		defer rc.mu.Unlock()
+		rc.requests = append(rc.requests, r)
+		rc.bodies = append(rc.bodies, body)
+		status := rc.statuses[len(rc.statuses)-1]
+		if n := len(rc.requests); n <= len(rc.statuses) {
+			status = rc.statuses[n-1]
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(rc.Close)
+	return rc
+}
+
+func (rc *receiver) count() int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return len(rc.requests)
+}
+
+func setup(t *testing.T, url string) (*Dispatcher, *store.SQLite, *models.Webhook) {
+	t.Helper()
+	st, err := store.NewSQLite(filepath.Join(t.TempDir(), "webhooks.db"), store.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { st.Close() })
+	wh := &models.Webhook{URL: url, Secret: "s3cret", Events: []string{models.EventRefreshCompleted}, Active: true}
+	if err := st.CreateWebhook(wh); err != nil {
+		t.Fatal(err)
+	}
+	return NewDispatcher(st, Options{MaxAttempts: 4, Backoff: time.Minute, MaxBackoff: 3 * time.Minute}), st, wh
+}
+
+// latestDelivery returns the webhook's latest delivery
+func latestDelivery(t *testing.T, st *store.SQLite, webhookID int64) models.WebhookDelivery {
+	t.Helper()
+	list, err := st.ListDeliveries(webhookID, 1)
+	if err != nil || len(list) != 1 {
+		t.Fatalf("deliveries: %v %v", list, err)
+	}
+	return list[0]
+}
+
+func TestDeliverySignedAndRetried(t *testing.T) {
+	rc := newReceiver(t, http.StatusInternalServerError, http.StatusBadGateway, http.StatusNoContent)
+	d, st, wh := setup(t, rc.URL)
+	if _, err := d.Enqueue(*wh, models.EventRefreshCompleted, map[string]any{"world": "Antica"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Attempt the delivery until it is no longer pending, checking the
+	// backoff scheduled after every failure
+	for attempt := 1; ; attempt++ {
+		del := latestDelivery(t, st, wh.ID)
+		if del.Status != models.DeliveryPending {
+			break
+		}
+		if attempt > 3 {
+			t.Fatalf("still pending after %d attempts", attempt-1)
+		}
+		before := time.Now()
+		d.deliver(&del)
+		after := latestDelivery(t, st, wh.ID)
+		if after.Status == models.DeliveryPending {
+			wait := after.NextAttemptAt.Sub(before)
+			if want := d.Backoff(attempt); wait < want || wait > want+5*time.Second {
+				t.Errorf("attempt %d: next attempt in %v, want %v", attempt, wait, want)
+			}
+		}
+	}
+
+	del := latestDelivery(t, st, wh.ID)
+	if del.Status != models.DeliveryDelivered || del.Attempts != 3 || del.DeliveredAt == nil {
+		t.Errorf("delivery is %s after %d attempts, want delivered after 3", del.Status, del.Attempts)
+	}
+	log, err := st.ListDeliveryAttempts(del.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantCodes := []int{500, 502, 204}
+	if len(log) != len(wantCodes) {
+		t.Fatalf("delivery log has %d attempts, want %d", len(log), len(wantCodes))
+	}
+	for i, a := range log {
+		if a.Attempt != i+1 || a.StatusCode != wantCodes[i] {
+			t.Errorf("log[%d]: attempt %d status %d, want attempt %d status %d", i, a.Attempt, a.StatusCode, i+1, wantCodes[i])
+		}
+		if (a.Error == "") != (wantCodes[i] == 204) {
+			t.Errorf("log[%d]: error %q", i, a.Error)
+		}
+	}
+
+	if rc.count() != 3 {
+		t.Fatalf("receiver got %d requests, want 3", rc.count())
+	}
+	for i, r := range rc.requests {
+		ts := r.Header.Get(TimestampHeader)
+		if _, err := strconv.ParseInt(ts, 10, 64); err != nil {
+			t.Errorf("request %d: bad timestamp %q", i, ts)
+		}
+		if got, want := r.Header.Get(SignatureHeader), Sign("s3cret", ts, rc.bodies[i]); got != want {
+			t.Errorf("request %d: signature %s, want %s", i, got, want)
+		}
+		if r.Header.Get(EventHeader) != models.EventRefreshCompleted || r.Header.Get(DeliveryHeader) != strconv.FormatInt(del.ID, 10) {
+			t.Errorf("request %d: headers %v", i, r.Header)
+		}
+		if string(rc.bodies[i]) != del.Payload {
+			t.Errorf("request %d: body %s, want %s", i, rc.bodies[i], del.Payload)
+		}
+	}
+}
+
+func TestDeliveryGivesUp(t *testing.T) {
+	rc := newReceiver(t, http.StatusServiceUnavailable)
+	d, st, wh := setup(t, rc.URL)
+	if _, err := d.Enqueue(*wh, models.EventPing, nil); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		del := latestDelivery(t, st, wh.ID)
+		d.deliver(&del)
+	}
+	del := latestDelivery(t, st, wh.ID)
+	if del.Status != models.DeliveryFailed || del.Attempts != 4 || del.NextAttemptAt != nil {
+		t.Errorf("delivery is %s after %d attempts (next %v), want failed after 4", del.Status, del.Attempts, del.NextAttemptAt)
+	}
+	if rc.count() != 4 {
+		t.Errorf("receiver got %d requests, want 4", rc.count())
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := NewDispatcher(nil, Options{Backoff: time.Minute, MaxBackoff: 5 * time.Minute})
+	want := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute}
+	for i, w := range want {
+		if got := d.Backoff(i + 1); got != w {
+			t.Errorf("Backoff(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+	if d := NewDispatcher(nil, Options{}); d.Backoff(1) != 30*time.Second || d.Backoff(20) != time.Hour || d.opts.MaxAttempts != 8 {
+		t.Errorf("defaults: %+v", d.opts)
+	}
+}
+
+func TestDeliveryDroppedWhenInactive(t *testing.T) {
+	rc := newReceiver(t, http.StatusInternalServerError)
+	d, st, wh := setup(t, rc.URL)
+	if _, err := d.Enqueue(*wh, models.EventPing, nil); err != nil {
+		t.Fatal(err)
+	}
+	del := latestDelivery(t, st, wh.ID)
+	d.deliver(&del)
+
+	// Deactivated while the retry is queued
+	wh.Active = false
+	if err := st.UpdateWebhook(wh); err != nil {
+		t.Fatal(err)
+	}
+	del = latestDelivery(t, st, wh.ID)
+	d.deliver(&del)
+	del = latestDelivery(t, st, wh.ID)
+	if del.Status != models.DeliveryDropped || del.NextAttemptAt != nil {
+		t.Errorf("delivery is %s (next %v), want dropped", del.Status, del.NextAttemptAt)
+	}
+	if rc.count() != 1 {
+		t.Errorf("receiver got %d requests, want 1", rc.count())
+	}
+}
+
+func TestDeliveryDroppedWhenDeleted(t *testing.T) {
+	rc := newReceiver(t, http.StatusOK)
+	d, st, wh := setup(t, rc.URL)
+	del, err := d.Enqueue(*wh, models.EventPing, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := st.DeleteWebhook(wh.ID); err != nil {
+		t.Fatal(err)
+	}
+	d.deliver(del)
+	if rc.count() != 0 {
+		t.Errorf("receiver got %d requests for a deleted webhook", rc.count())
+	}
+}